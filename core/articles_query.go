@@ -0,0 +1,418 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// articlesQuery holds the pagination, sorting and field selection options
+// of a request to the articles list.
+type articlesQuery struct {
+	paginated bool
+	page      int
+	perPage   int
+	useCursor bool
+	cursor    *articlesCursor
+	sort      string
+	desc      bool
+	fields    []string
+}
+
+// articlesCursor points at the last article of a page, or at the first one
+// for a cursor to the previous page, by its sort key, so that the pages do
+// not shift when articles are added or removed.
+type articlesCursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Before bool   `json:"b,omitempty"`
+	Key    string `json:"k"`
+	Slug   string `json:"i"`
+}
+
+// articlesQueryError is an invalid parameter of a request to the articles
+// list, answered with 400 Bad Request.
+type articlesQueryError struct {
+	message string
+}
+
+func (e *articlesQueryError) Error() string {
+	return e.message
+}
+
+func invalidQuery(format string, args ...any) error {
+	return &articlesQueryError{message: fmt.Sprintf(format, args...)}
+}
+
+// articlesQueryStatus returns the status of a response failing with err,
+// 400 for an invalid query and 500 otherwise.
+func articlesQueryStatus(err error) int {
+	var queryErr *articlesQueryError
+	if errors.As(err, &queryErr) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// articleSortKeys maps the accepted sort values to the function returning
+// the key an article is sorted by. Keys are compared as strings.
+var articleSortKeys = map[string]func(article *structs.Article) string{
+	"path": func(article *structs.Article) string {
+		return article.Path
+	},
+	"publicationDate": func(article *structs.Article) string {
+		// dates which cannot be parsed come after the others
+		if at, ok := parseArticleDate(article.PublicationDate); ok {
+			return "0" + at.UTC().Format("20060102150405.000000000")
+		}
+		return "1" + article.PublicationDate
+	},
+	"title": func(article *structs.Article) string {
+		return strings.ToLower(article.Title)
+	},
+	"lastModified": func(article *structs.Article) string {
+		return article.LastModified.UTC().Format("20060102150405.000000000")
+	},
+	"weight": func(article *structs.Article) string {
+		// flip the sign bit so that negative weights come first
+		return fmt.Sprintf("%020d", uint64(int64(article.Weight))^(1<<63))
+	},
+}
+
+// articleFields lists the names accepted by the fields parameter.
+var articleFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(structs.Article{})
+	for i := 0; i < t.NumField(); i++ {
+		fields[t.Field(i).Name] = true
+	}
+
+	return fields
+}()
+
+// parseArticlesQuery parses the query parameters of a request to the
+// articles list. Without page, perPage or cursor every article is returned.
+func parseArticlesQuery(values url.Values) (articlesQuery, error) {
+	query := articlesQuery{
+		page:    1,
+		perPage: defaultPerPage,
+		sort:    "path",
+	}
+
+	if sortBy := values.Get("sort"); sortBy != "" {
+		query.sort, query.desc = strings.CutPrefix(sortBy, "-")
+		if _, ok := articleSortKeys[query.sort]; !ok {
+			return query, invalidQuery("invalid sort: %s", sortBy)
+		}
+	}
+
+	if values.Has("page") {
+		page, err := strconv.Atoi(values.Get("page"))
+		if err != nil || page < 1 {
+			return query, invalidQuery("invalid page")
+		}
+		query.page = page
+		query.paginated = true
+	}
+
+	if values.Has("perPage") {
+		perPage, err := strconv.Atoi(values.Get("perPage"))
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			return query, invalidQuery("invalid perPage, must be between 1 and %d", maxPerPage)
+		}
+		query.perPage = perPage
+		query.paginated = true
+	}
+
+	if values.Has("cursor") {
+		if values.Has("page") {
+			return query, invalidQuery("page and cursor are mutually exclusive")
+		}
+		query.useCursor = true
+		query.paginated = true
+
+		if encoded := values.Get("cursor"); encoded != "" {
+			cursor, err := decodeArticlesCursor(encoded)
+			if err != nil || cursor.Sort != query.sort || cursor.Desc != query.desc {
+				return query, invalidQuery("invalid cursor")
+			}
+			query.cursor = cursor
+		}
+	}
+
+	if fields := values.Get("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if !articleFields[field] {
+				return query, invalidQuery("invalid field: %s", field)
+			}
+			query.fields = append(query.fields, field)
+		}
+	}
+
+	return query, nil
+}
+
+// validate checks the options of a query which was not necessarily built
+// by parseArticlesQuery.
+func (q articlesQuery) validate() error {
+	if _, ok := articleSortKeys[q.sort]; !ok {
+		return invalidQuery("invalid sort: %s", q.sort)
+	}
+
+	if q.paginated && (q.page < 1 || q.perPage < 1 || q.perPage > maxPerPage) {
+		return invalidQuery("invalid page or perPage")
+	}
+
+	if q.cursor != nil && (q.cursor.Sort != q.sort || q.cursor.Desc != q.desc) {
+		return invalidQuery("invalid cursor")
+	}
+
+	for _, field := range q.fields {
+		if !articleFields[field] {
+			return invalidQuery("invalid field: %s", field)
+		}
+	}
+
+	return nil
+}
+
+// isDefault reports whether the query returns the whole list as is.
+func (q articlesQuery) isDefault() bool {
+	return !q.paginated && q.sort == "path" && !q.desc && q.fields == nil
+}
+
+// sortedArticles returns the articles in the order of the query, ties being
+// broken by slug.
+func (q articlesQuery) sortedArticles(articles []structs.Article) ([]structs.Article, []string) {
+	sortKey := articleSortKeys[q.sort]
+
+	sorted := make([]structs.Article, len(articles))
+	copy(sorted, articles)
+
+	keys := make([]string, len(sorted))
+	for i := range sorted {
+		keys[i] = sortKey(&sorted[i])
+	}
+
+	sort.Sort(articlesByKey{articles: sorted, keys: keys, desc: q.desc})
+	return sorted, keys
+}
+
+// articlesPage returns the articles requested by the query, u being the
+// URL of the request the pagination links are built from.
+func (q articlesQuery) articlesPage(u *url.URL, articles []structs.Article) (structs.ArticlesPage, error) {
+	page := structs.ArticlesPage{
+		Articles: articles,
+		Total:    len(articles),
+	}
+
+	if q.isDefault() {
+		return page, nil
+	}
+
+	err := q.validate()
+	if err != nil {
+		return page, err
+	}
+
+	sorted, keys := q.sortedArticles(articles)
+	start, end := q.paginate(sorted, keys)
+
+	if q.paginated {
+		page.PerPage = q.perPage
+		page.TotalPages = (len(sorted) + q.perPage - 1) / q.perPage
+
+		if q.useCursor {
+			if end < len(sorted) {
+				page.Next = pageLink(u, "cursor", q.cursorNext(sorted, keys, end))
+			}
+			if start > 0 {
+				page.Prev = pageLink(u, "cursor", q.cursorPrev(sorted, keys, start))
+			}
+		} else {
+			page.Page = q.page
+			if end < len(sorted) {
+				page.Next = pageLink(u, "page", strconv.Itoa(q.page+1))
+			}
+			if q.page > 1 {
+				page.Prev = pageLink(u, "page", strconv.Itoa(min(q.page-1, max(page.TotalPages, 1))))
+			}
+		}
+	}
+
+	selected, err := q.selectFields(sorted[start:end])
+	if err != nil {
+		return page, err
+	}
+	page.Articles = selected
+
+	return page, nil
+}
+
+// paginate returns the bounds of the requested page in the sorted articles.
+func (q articlesQuery) paginate(sorted []structs.Article, keys []string) (int, int) {
+	if !q.paginated {
+		return 0, len(sorted)
+	}
+
+	start := (q.page - 1) * q.perPage
+	if q.useCursor {
+		start = 0
+		if q.cursor != nil && q.cursor.Before {
+			end := sort.Search(len(sorted), func(i int) bool {
+				return !q.after(q.cursor.Key, q.cursor.Slug, keys[i], sorted[i].Slug)
+			})
+			return max(end-q.perPage, 0), end
+		}
+		if q.cursor != nil {
+			start = sort.Search(len(sorted), func(i int) bool {
+				return q.after(keys[i], sorted[i].Slug, q.cursor.Key, q.cursor.Slug)
+			})
+		}
+	}
+
+	start = min(start, len(sorted))
+	return start, min(start+q.perPage, len(sorted))
+}
+
+// after reports whether the article with key and slug comes after the one
+// with otherKey and otherSlug in the order of the query.
+func (q articlesQuery) after(key, slug, otherKey, otherSlug string) bool {
+	if key != otherKey {
+		return (key > otherKey) != q.desc
+	}
+	if slug != otherSlug {
+		return (slug > otherSlug) != q.desc
+	}
+
+	return false
+}
+
+// cursorNext returns the cursor to the page starting at the end index of
+// the sorted articles.
+func (q articlesQuery) cursorNext(sorted []structs.Article, keys []string, end int) string {
+	if end == 0 {
+		return ""
+	}
+
+	return q.encodeCursor(articlesCursor{Key: keys[end-1], Slug: sorted[end-1].Slug})
+}
+
+// cursorPrev returns the cursor to the page ending before the start index
+// of the sorted articles.
+func (q articlesQuery) cursorPrev(sorted []structs.Article, keys []string, start int) string {
+	if start < len(sorted) {
+		return q.encodeCursor(articlesCursor{Before: true, Key: keys[start], Slug: sorted[start].Slug})
+	}
+
+	// past the end, the previous page is the last one
+	return q.cursorNext(sorted, keys, max(len(sorted)-q.perPage, 0))
+}
+
+func (q articlesQuery) encodeCursor(cursor articlesCursor) string {
+	cursor.Sort = q.sort
+	cursor.Desc = q.desc
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeArticlesCursor(encoded string) (*articlesCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor articlesCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// selectFields returns the articles with only the fields of the query.
+func (q articlesQuery) selectFields(articles []structs.Article) (any, error) {
+	if q.fields == nil {
+		return articles, nil
+	}
+
+	selected := make([]map[string]json.RawMessage, 0, len(articles))
+	for _, article := range articles {
+		data, err := json.Marshal(article)
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+		err = json.Unmarshal(data, &all)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make(map[string]json.RawMessage, len(q.fields))
+		for _, field := range q.fields {
+			fields[field] = all[field]
+		}
+		selected = append(selected, fields)
+	}
+
+	return selected, nil
+}
+
+// pageLink returns the URL of the current request with its pagination
+// parameters replaced.
+func pageLink(u *url.URL, param string, value string) string {
+	values := u.Query()
+	values.Set(param, value)
+
+	link := url.URL{Path: u.Path, RawQuery: values.Encode()}
+	return link.String()
+}
+
+type articlesByKey struct {
+	articles []structs.Article
+	keys     []string
+	desc     bool
+}
+
+func (a articlesByKey) Len() int { return len(a.articles) }
+
+func (a articlesByKey) Swap(i, j int) {
+	a.articles[i], a.articles[j] = a.articles[j], a.articles[i]
+	a.keys[i], a.keys[j] = a.keys[j], a.keys[i]
+}
+
+func (a articlesByKey) Less(i, j int) bool {
+	if a.keys[i] != a.keys[j] {
+		return (a.keys[i] < a.keys[j]) != a.desc
+	}
+
+	return (a.articles[i].Slug < a.articles[j].Slug) != a.desc
+}
@@ -0,0 +1,211 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// queryArticles are listed by path in the order of their slugs.
+var queryArticles = []structs.Article{
+	{Slug: "a", Path: "en/a.md", Title: "Delta", PublicationDate: "2024-01-05", Weight: 2},
+	{Slug: "b", Path: "en/b.md", Title: "alpha", PublicationDate: "", Weight: -3},
+	{Slug: "c", Path: "en/c.md", Title: "Charlie", PublicationDate: "2023-12-31T10:00:00Z", Weight: 0},
+	{Slug: "d", Path: "en/d.md", Title: "bravo", PublicationDate: "someday", Weight: 2},
+	{Slug: "e", Path: "en/e.md", Title: "Echo", PublicationDate: "2024-01-01", Weight: 10},
+}
+
+func pageSlugs(t *testing.T, page structs.ArticlesPage) []string {
+	t.Helper()
+
+	articles, ok := page.Articles.([]structs.Article)
+	if !ok {
+		t.Fatalf("articles are %T, want []structs.Article", page.Articles)
+	}
+
+	slugs := make([]string, len(articles))
+	for i, article := range articles {
+		slugs[i] = article.Slug
+	}
+
+	return slugs
+}
+
+func TestArticlesQuerySort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"a", "b", "c", "d", "e"}},
+		{"title", []string{"b", "d", "c", "a", "e"}},
+		{"-title", []string{"e", "a", "c", "d", "b"}},
+		// dates which cannot be parsed, empty ones included, come last
+		{"publicationDate", []string{"c", "e", "a", "b", "d"}},
+		{"-publicationDate", []string{"d", "b", "a", "e", "c"}},
+		// ties are broken by slug
+		{"weight", []string{"b", "c", "a", "d", "e"}},
+		{"-weight", []string{"e", "d", "a", "c", "b"}},
+	}
+
+	for _, test := range tests {
+		values := url.Values{"perPage": {"10"}}
+		if test.sort != "" {
+			values.Set("sort", test.sort)
+		}
+
+		query, err := parseArticlesQuery(values)
+		if err != nil {
+			t.Fatalf("sort %q: parseArticlesQuery() failed: %v", test.sort, err)
+		}
+
+		page, err := query.articlesPage(&url.URL{Path: "/docs/articles/en"}, queryArticles)
+		if err != nil {
+			t.Fatalf("sort %q: articlesPage() failed: %v", test.sort, err)
+		}
+
+		if got := pageSlugs(t, page); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("sort %q = %v, want %v", test.sort, got, test.want)
+		}
+	}
+}
+
+func TestArticlesQueryPages(t *testing.T) {
+	u := &url.URL{Path: "/docs/articles/en", RawQuery: "page=2&perPage=2"}
+	query, err := parseArticlesQuery(u.Query())
+	if err != nil {
+		t.Fatalf("parseArticlesQuery() failed: %v", err)
+	}
+
+	page, err := query.articlesPage(u, queryArticles)
+	if err != nil {
+		t.Fatalf("articlesPage() failed: %v", err)
+	}
+
+	if got := pageSlugs(t, page); !reflect.DeepEqual(got, []string{"c", "d"}) {
+		t.Errorf("page 2 = %v, want [c d]", got)
+	}
+	if page.Total != 5 || page.TotalPages != 3 || page.Page != 2 || page.PerPage != 2 {
+		t.Errorf("page 2 = %+v, want 5 articles in 3 pages", page)
+	}
+	if page.Next != "/docs/articles/en?page=3&perPage=2" || page.Prev != "/docs/articles/en?page=1&perPage=2" {
+		t.Errorf("page 2 links = %q, %q", page.Next, page.Prev)
+	}
+}
+
+func TestArticlesQueryCursor(t *testing.T) {
+	u := &url.URL{Path: "/docs/articles/en", RawQuery: "sort=-weight&perPage=2&cursor="}
+
+	// walk forward to the end, then back to the start
+	var forward, backward [][]string
+	last := ""
+	for link := u.String(); link != ""; {
+		page, next, _ := cursorPage(t, link, queryArticles)
+		forward = append(forward, page)
+		last, link = link, next
+	}
+	for link := last; link != ""; {
+		page, _, prev := cursorPage(t, link, queryArticles)
+		backward = append(backward, page)
+		link = prev
+	}
+
+	wantForward := [][]string{{"e", "d"}, {"a", "c"}, {"b"}}
+	if !reflect.DeepEqual(forward, wantForward) {
+		t.Errorf("forward pages = %v, want %v", forward, wantForward)
+	}
+
+	wantBackward := [][]string{{"b"}, {"a", "c"}, {"e", "d"}}
+	if !reflect.DeepEqual(backward, wantBackward) {
+		t.Errorf("backward pages = %v, want %v", backward, wantBackward)
+	}
+
+	// a cursor keeps its position when an article is added before it
+	_, next, _ := cursorPage(t, u.String(), queryArticles)
+	added := append([]structs.Article{{Slug: "f", Path: "en/f.md", Weight: 20}}, queryArticles...)
+	if got, _, _ := cursorPage(t, next, added); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("page after an insertion = %v, want [a c]", got)
+	}
+}
+
+// cursorPage returns the slugs of the page of link over articles and its
+// next and previous links.
+func cursorPage(t *testing.T, link string, articles []structs.Article) ([]string, string, string) {
+	t.Helper()
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("invalid link %q: %v", link, err)
+	}
+
+	query, err := parseArticlesQuery(u.Query())
+	if err != nil {
+		t.Fatalf("parseArticlesQuery(%s) failed: %v", link, err)
+	}
+
+	page, err := query.articlesPage(u, articles)
+	if err != nil {
+		t.Fatalf("articlesPage(%s) failed: %v", link, err)
+	}
+
+	return pageSlugs(t, page), page.Next, page.Prev
+}
+
+func TestArticlesQueryFields(t *testing.T) {
+	query, err := parseArticlesQuery(url.Values{"fields": {"Slug, Title"}, "perPage": {"1"}})
+	if err != nil {
+		t.Fatalf("parseArticlesQuery() failed: %v", err)
+	}
+
+	page, err := query.articlesPage(&url.URL{Path: "/docs/articles/en"}, queryArticles)
+	if err != nil {
+		t.Fatalf("articlesPage() failed: %v", err)
+	}
+
+	selected, ok := page.Articles.([]map[string]json.RawMessage)
+	if !ok || len(selected) != 1 {
+		t.Fatalf("articles = %#v, want one article with selected fields", page.Articles)
+	}
+	if len(selected[0]) != 2 || string(selected[0]["Slug"]) != `"a"` || string(selected[0]["Title"]) != `"Delta"` {
+		t.Errorf("selected fields = %s", selected[0])
+	}
+}
+
+func TestArticlesQueryInvalid(t *testing.T) {
+	otherSort, _ := parseArticlesQuery(url.Values{"sort": {"title"}, "cursor": {""}})
+	cursor := otherSort.encodeCursor(articlesCursor{Key: "x", Slug: "x"})
+
+	for _, raw := range []string{
+		"sort=size",
+		"page=0",
+		"page=x",
+		"perPage=101",
+		"page=1&cursor=",
+		"cursor=not-base64!",
+		"sort=weight&cursor=" + cursor,
+		"fields=Slug,Secret",
+	} {
+		values, _ := url.ParseQuery(raw)
+		_, err := parseArticlesQuery(values)
+		if err == nil || articlesQueryStatus(err) != 400 {
+			t.Errorf("parseArticlesQuery(%s) = %v, want a 400 error", raw, err)
+		}
+	}
+
+	query := articlesQuery{paginated: true, page: 1, perPage: 1, sort: "size"}
+	_, err := query.articlesPage(&url.URL{}, queryArticles)
+	if err == nil || articlesQueryStatus(err) != 400 {
+		t.Errorf("articlesPage() with an invalid sort = %v, want a 400 error", err)
+	}
+}
@@ -13,48 +13,91 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/allegro/bigcache/v3"
+	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/dgraph-io/ristretto"
 	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
 	big_cache_backend "github.com/eko/gocache/store/bigcache/v4"
 	go_cache_backend "github.com/eko/gocache/store/go_cache/v4"
 	ristretto_backend "github.com/eko/gocache/store/ristretto/v4"
 	go_cache "github.com/patrickmn/go-cache"
 	redis "github.com/redis/go-redis/v9"
 	"github.com/vanilla-os/Chronos/settings"
-	redis_backend "github.com/vanilla-os/Chronos/utils"
+	custom_backend "github.com/vanilla-os/Chronos/utils"
+	bolt "go.etcd.io/bbolt"
 )
 
 var (
-	cacheManager *cache.Cache[[]byte]
+	cacheManager cache.CacheInterface[[]byte]
+
+	// cacheBackend is the name of the backend in use
+	cacheBackend string
+
+	// localCache is the in-process tier chained in front of the redis
+	// backend, if any, and localCacheUsage reports its usage
+	localCache      cache.SetterCacheInterface[[]byte]
+	localCacheUsage func(ctx context.Context) (int64, int64, error)
+
+	// cacheUsage reports the number of keys and the memory used by the
+	// backend, -1 when unknown
+	cacheUsage = func(ctx context.Context) (int64, int64, error) {
+		return -1, -1, nil
+	}
+
+	// redisClient is the client of the redis backend, also used for the
+	// coordination between instances
+	redisClient *redis.Client
 )
 
 func NewRistrettoCache() (*cache.Cache[[]byte], error) {
+	// costs are the entries size in bytes, see setCacheEntry
 	ristrettoCache, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: 1000,
-		MaxCost:     100,
-		BufferItems: 64,
+		NumCounters:        settings.Cnf.RistrettoNumCounters,
+		MaxCost:            settings.Cnf.RistrettoMaxCost,
+		BufferItems:        64,
+		IgnoreInternalCost: true,
+		Metrics:            true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to create ristretto cache: %w", err)
 	}
 
+	// deletions count as evictions in the ristretto metrics
+	cacheUsage = func(ctx context.Context) (int64, int64, error) {
+		metrics := ristrettoCache.Metrics
+		keys := int64(metrics.KeysAdded() - metrics.KeysEvicted())
+		cost := int64(metrics.CostAdded() - metrics.CostEvicted())
+		return keys, cost, nil
+	}
+
 	ristrettoStore := ristretto_backend.NewRistretto(ristrettoCache)
-	cacheManager = cache.New[[]byte](ristrettoStore)
+	cacheManager := cache.New[[]byte](ristrettoStore)
 
 	return cacheManager, nil
 }
 
 func NewBigCache() (*cache.Cache[[]byte], error) {
-	bigcacheClient, err := bigcache.New(context.Background(), bigcache.DefaultConfig(5*time.Minute))
+	bigcacheConfig := bigcache.DefaultConfig(settings.Cnf.BigCacheLifeWindow)
+	bigcacheConfig.HardMaxCacheSize = settings.Cnf.BigCacheHardMaxCacheSize
+
+	bigcacheClient, err := bigcache.New(context.Background(), bigcacheConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create bigcache client: %w", err)
 	}
 
 	bigcacheStore := big_cache_backend.NewBigcache(bigcacheClient)
 
+	cacheUsage = func(ctx context.Context) (int64, int64, error) {
+		return int64(bigcacheClient.Len()), int64(bigcacheClient.Capacity()), nil
+	}
+
 	cacheManager := cache.New[[]byte](bigcacheStore)
 	return cacheManager, nil
 }
@@ -88,15 +131,15 @@ func NewRedisCache() (*cache.Cache[[]byte], error) {
 		redisDB = 0
 	}
 
-	redisClient := redis.NewClient(&redis.Options{
+	redisClient = redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", redisServer, redisPort),
 		Username: redisUsername,
 		Password: redisPassword,
 		DB:       redisDB,
 	})
 
-	redisStore := redis_backend.NewRedis(redisClient)
-	cacheManager = cache.New[[]byte](redisStore)
+	redisStore := custom_backend.NewRedis(redisClient)
+	cacheManager := cache.New[[]byte](redisStore)
 
 	status := redisClient.Ping(context.Background())
 	if status.Err() != nil {
@@ -106,17 +149,92 @@ func NewRedisCache() (*cache.Cache[[]byte], error) {
 		return nil, fmt.Errorf("unable to connect to Redis server: unexpected response: %s", status.Val())
 	}
 
+	cacheUsage = redisUsage
+
+	return cacheManager, nil
+}
+
+func NewMemcacheCache() (*cache.Cache[[]byte], error) {
+	servers := settings.Cnf.MemcacheServers
+	if len(servers) == 0 {
+		log.Println("(cache): No Memcached servers specified, using default: localhost:11211")
+		servers = []string{"localhost:11211"}
+	}
+
+	memcacheClient := memcache.New(servers...)
+	memcacheClient.Timeout = settings.Cnf.MemcacheTimeout
+
+	err := memcacheClient.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Memcached servers: %w", err)
+	}
+
+	memcacheStore := custom_backend.NewMemcache(memcacheClient, settings.Cnf.MemcacheKeyPrefix)
+
+	cacheManager := cache.New[[]byte](memcacheStore)
 	return cacheManager, nil
 }
 
 func NewGoCache() (*cache.Cache[[]byte], error) {
-	gocacheClient := go_cache.New(5*time.Minute, 10*time.Minute)
+	gocacheClient := go_cache.New(settings.Cnf.GoCacheDefaultExpiration, settings.Cnf.GoCacheCleanupInterval)
 	gocacheStore := go_cache_backend.NewGoCache(gocacheClient)
 
+	cacheUsage = func(ctx context.Context) (int64, int64, error) {
+		items := gocacheClient.Items()
+
+		var size int64
+		for _, item := range items {
+			if data, ok := item.Object.([]byte); ok {
+				size += int64(len(data))
+			}
+		}
+
+		return int64(len(items)), size, nil
+	}
+
 	cacheManager := cache.New[[]byte](gocacheStore)
 	return cacheManager, nil
 }
 
+func NewDiskCache() (*cache.Cache[[]byte], error) {
+	path := settings.Cnf.DiskCachePath
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create disk cache directory: %w", err)
+	}
+
+	// a short timeout, so a database locked by another process is reported
+	// instead of blocking the startup
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open disk cache %s: %w", path, err)
+	}
+
+	diskStore, err := custom_backend.NewBbolt(db)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize disk cache %s: %w", path, err)
+	}
+
+	go func() {
+		for range time.Tick(settings.Cnf.DiskCacheCleanupInterval) {
+			deleted, err := diskStore.Cleanup()
+			if err != nil {
+				log.Printf("(cache): Unable to clean up disk cache: %v\n", err)
+			} else if deleted > 0 {
+				log.Printf("(cache): Deleted %d expired entries from disk cache\n", deleted)
+			}
+		}
+	}()
+
+	cacheUsage = func(ctx context.Context) (int64, int64, error) {
+		return diskStore.Usage()
+	}
+
+	cacheManager := cache.New[[]byte](diskStore)
+	return cacheManager, nil
+}
+
 func InitCacheManager() error {
 	var err error
 	backend := settings.Cnf.CacheBackend
@@ -134,6 +252,10 @@ func InitCacheManager() error {
 		cacheManager, err = NewRedisCache()
 	case "gocache":
 		cacheManager, err = NewGoCache()
+	case "disk":
+		cacheManager, err = NewDiskCache()
+	case "memcache":
+		cacheManager, err = NewMemcacheCache()
 	default:
 		err = fmt.Errorf("unknown cache backend: %s", backend)
 	}
@@ -142,6 +264,107 @@ func InitCacheManager() error {
 		return fmt.Errorf("unable to initialize cache manager (backend: %s): %w", backend, err)
 	}
 
+	cacheBackend = backend
 	log.Printf("(cache): Cache manager initialized with backend: %s", backend)
+
+	if settings.Cnf.CacheLocalTier != "" {
+		return initLocalCacheTier(backend)
+	}
+
 	return nil
 }
+
+// initLocalCacheTier chains an in-process cache in front of a shared
+// backend, so that reads are served locally when possible.
+func initLocalCacheTier(backend string) error {
+	tier := settings.Cnf.CacheLocalTier
+	if backend != "redis" && backend != "memcache" {
+		return fmt.Errorf("cacheLocalTier requires the redis or memcache cache backend")
+	}
+
+	// the constructors replace the usage reporter of the backend
+	shared := cacheManager.(*cache.Cache[[]byte])
+	sharedUsage := cacheUsage
+
+	var local *cache.Cache[[]byte]
+	var err error
+	switch tier {
+	case "ristretto":
+		local, err = NewRistrettoCache()
+	case "gocache":
+		local, err = NewGoCache()
+	default:
+		err = fmt.Errorf("unsupported local cache tier: %s", tier)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to initialize local cache tier (%s): %w", tier, err)
+	}
+
+	localCacheUsage = cacheUsage
+	cacheUsage = sharedUsage
+
+	localCache = &localTierCache{
+		SetterCacheInterface: local,
+		ttl:                  settings.Cnf.CacheLocalTTL,
+	}
+	cacheManager = cache.NewChain[[]byte](localCache, shared)
+
+	log.Printf("(cache): Local cache tier initialized with backend: %s, ttl: %s", tier, settings.Cnf.CacheLocalTTL)
+	return nil
+}
+
+// localTierCache writes the entries of the local cache tier with its own
+// expiration, and with their size as cost also when the chain writes them
+// back from the shared tier. The content version is never kept locally, so
+// that it is always read from the shared tier: unlike the versioned keys, it
+// changes when another instance reloads.
+type localTierCache struct {
+	cache.SetterCacheInterface[[]byte]
+	ttl time.Duration
+}
+
+func (c *localTierCache) Set(ctx context.Context, key any, object []byte, options ...store.Option) error {
+	if key == contentVersionKey {
+		return nil
+	}
+
+	options = append(options, store.WithCost(int64(len(object))), store.WithExpiration(c.ttl))
+	return c.SetterCacheInterface.Set(ctx, key, object, options...)
+}
+
+// clearLocalCache empties the local cache tier, if any, so that the next
+// reads hit the shared tier.
+func clearLocalCache(ctx context.Context) {
+	if localCache == nil {
+		return
+	}
+
+	err := localCache.Clear(ctx)
+	if err != nil {
+		log.Printf("(cache): Unable to clear local cache tier: %v\n", err)
+	}
+}
+
+// redisUsage reports the keys of the Redis database and the memory used by
+// the whole server, which may be shared with other applications.
+func redisUsage(ctx context.Context) (int64, int64, error) {
+	keys, err := redisClient.DBSize(ctx).Result()
+	if err != nil {
+		return -1, -1, err
+	}
+
+	info, err := redisClient.Info(ctx, "memory").Result()
+	if err != nil {
+		return keys, -1, err
+	}
+
+	for _, line := range strings.Split(info, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "used_memory:")
+		if ok {
+			memory, err := strconv.ParseInt(value, 10, 64)
+			return keys, memory, err
+		}
+	}
+
+	return keys, -1, nil
+}
@@ -0,0 +1,181 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+var errSyncInProgress = errors.New("another instance is synchronizing the repositories")
+
+// getCacheStats reports the state of the cache backend.
+func getCacheStats(ctx context.Context) structs.CacheStatsResponse {
+	stats := structs.CacheStatsResponse{
+		Backend:       cacheBackend,
+		Hits:          responseHits.Load(),
+		Misses:        responseMisses.Load(),
+		BackendHits:   cacheHits.Load(),
+		BackendMisses: cacheMisses.Load(),
+	}
+
+	if s := getSnapshot(); s != nil {
+		stats.ContentVersion = s.version
+	}
+
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	var err error
+	stats.Keys, stats.Memory, err = cacheUsage(ctx)
+	if err != nil {
+		log.Printf("(cache): Unable to get backend usage: %v\n", err)
+	}
+
+	if localCacheUsage != nil {
+		stats.LocalTier = settings.Cnf.CacheLocalTier
+		stats.LocalKeys, stats.LocalMemory, err = localCacheUsage(ctx)
+		if err != nil {
+			log.Printf("(cache): Unable to get local tier usage: %v\n", err)
+		}
+	}
+
+	return stats
+}
+
+// purgeCache removes entries from the cache backend: those of the served
+// content version, those of a repository or those whose key matches a valid
+// path.Match pattern. Only the keys written by Chronos are removed, the
+// backend may be shared. The repositories are then rebuilt from the current
+// checkouts and the served responses rendered again. The number of purged
+// keys is returned.
+func purgeCache(ctx context.Context, repoId string, pattern string) (int, error) {
+	purged, err := purgeCacheEntries(ctx, repoId, pattern)
+	if err != nil {
+		return purged, err
+	}
+
+	clearLocalCache(ctx)
+
+	// another instance synchronizing the repositories rebuilds them anyway
+	_, err = prepareReposExclusively(false)
+	republishSnapshot()
+	if err != nil {
+		return purged, fmt.Errorf("purged %d entries, but unable to rebuild the repositories: %w", purged, err)
+	}
+
+	return purged, nil
+}
+
+func purgeCacheEntries(ctx context.Context, repoId string, pattern string) (int, error) {
+	s := getSnapshot()
+	if s == nil {
+		return 0, errors.New("repos not loaded")
+	}
+
+	if repoId == "" && pattern == "" {
+		err := cacheManager.Invalidate(ctx, store.WithInvalidateTags([]string{versionTag(s.version)}))
+		if err != nil {
+			return 0, fmt.Errorf("unable to invalidate content version %s: %w", s.version, err)
+		}
+
+		err = cacheManager.Delete(ctx, contentVersionKey)
+		if err != nil {
+			return 0, fmt.Errorf("unable to delete %s: %w", contentVersionKey, err)
+		}
+
+		log.Println("(cache): Purged all entries")
+		return len(allRepoCacheKeys(s)) + 2, nil // the index and the content version
+	}
+
+	var keys []string
+	if repoId != "" {
+		repo, err := s.getRepo(repoId)
+		if err != nil {
+			return 0, err
+		}
+		keys = repoCacheKeys(s.version, repo)
+	} else {
+		for _, key := range append([]string{reposIndexKey(s.version)}, allRepoCacheKeys(s)...) {
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		err := cacheManager.Delete(ctx, key)
+		if err != nil {
+			return 0, fmt.Errorf("unable to delete %s: %w", key, err)
+		}
+	}
+
+	// the content version is reset so that the rebuild writes the purged
+	// keys again instead of keeping the version as is
+	if len(keys) > 0 {
+		err := cacheManager.Delete(ctx, contentVersionKey)
+		if err != nil {
+			return len(keys), fmt.Errorf("unable to delete %s: %w", contentVersionKey, err)
+		}
+	}
+
+	if repoId != "" {
+		log.Printf("(cache): Purged repo %s\n", repoId)
+	} else {
+		log.Printf("(cache): Purged %d entries matching %s\n", len(keys), pattern)
+	}
+	return len(keys), nil
+}
+
+// allRepoCacheKeys returns the keys storeRepoInCache writes for the
+// repositories of a snapshot.
+func allRepoCacheKeys(s *snapshot) []string {
+	var keys []string
+	for _, repo := range s.repos {
+		keys = append(keys, repoCacheKeys(s.version, repo)...)
+	}
+
+	return keys
+}
+
+// repoCacheKeys returns the keys storeRepoInCache writes for a repository.
+func repoCacheKeys(version string, repo *structs.Repo) []string {
+	keys := []string{repoMetaKey(version, repo.Id)}
+	for lang, articles := range repo.ArticlesGrouped {
+		keys = append(keys, repoListKey(version, repo.Id, lang))
+		for _, article := range articles {
+			keys = append(keys, repoArticleKey(version, repo.Id, lang, article.Slug))
+		}
+	}
+
+	return keys
+}
+
+// warmupCache rebuilds the repositories and the cache from the current
+// checkouts, without synchronizing them, returning the new content version.
+func warmupCache() (string, error) {
+	prepared, err := prepareReposExclusively(false)
+	if err != nil {
+		return "", err
+	}
+	if !prepared {
+		return "", errSyncInProgress
+	}
+
+	return getSnapshot().version, nil
+}
@@ -0,0 +1,256 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// The repositories are stored in the cache backend under granular keys, so
+// that no single entry grows with the whole corpus, namespaced by content
+// version:
+//
+//	repos:version                              content version being served
+//	repos:{version}:index                      IDs of the cached repositories
+//	repo:{version}:{id}:meta                   repository without its articles
+//	repo:{version}:{id}:{lang}:list            slugs of the articles in a language
+//	repo:{version}:{id}:{lang}:article:{slug}  an article and its Git history
+//
+// The keys of a version are never rewritten with other content: a new
+// version is written aside, then repos:version is pointed at it and the
+// previous one is invalidated, so readers never mix two versions. Every key
+// of a version is tagged with version:{version} only, so that the tag lists
+// are dropped along with the version.
+
+// cacheHits and cacheMisses count the reads of getCacheEntry.
+var cacheHits, cacheMisses atomic.Uint64
+
+func reposIndexKey(version string) string {
+	return fmt.Sprintf("repos:%s:index", version)
+}
+
+func repoMetaKey(version string, repoId string) string {
+	return fmt.Sprintf("repo:%s:%s:meta", version, repoId)
+}
+
+func repoListKey(version string, repoId string, lang string) string {
+	return fmt.Sprintf("repo:%s:%s:%s:list", version, repoId, lang)
+}
+
+func repoArticleKey(version string, repoId string, lang string, slug string) string {
+	return fmt.Sprintf("repo:%s:%s:%s:article:%s", version, repoId, lang, slug)
+}
+
+func versionTag(version string) string {
+	return "version:" + version
+}
+
+// cachedRepo is the value stored under a repository meta key.
+type cachedRepo struct {
+	Repo             structs.Repo
+	ArticleLanguages []string // languages with an article list
+}
+
+// cachedArticle is the value stored under an article key.
+type cachedArticle struct {
+	Article structs.Article
+	History []structs.ArticleCommit
+}
+
+// setCacheEntry marshals and stores a value, using its size as cost so that
+// size bounded backends account for it properly.
+func setCacheEntry(ctx context.Context, key string, value any, tags ...string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %w", key, err)
+	}
+
+	err = cacheManager.Set(ctx, key, data,
+		store.WithCost(int64(len(data))),
+		store.WithExpiration(settings.Cnf.CacheTTL),
+		store.WithTags(tags),
+		store.WithSynchronousSet(),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to cache %s (%d bytes): %w", key, len(data), err)
+	}
+
+	return nil
+}
+
+// getCacheEntry reads and unmarshals a value stored by setCacheEntry.
+func getCacheEntry(ctx context.Context, key string, value any) error {
+	data, err := cacheManager.Get(ctx, key)
+	if err != nil {
+		cacheMisses.Add(1)
+		return fmt.Errorf("unable to get %s from cache: %w", key, err)
+	}
+	cacheHits.Add(1)
+
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// storeReposInCache writes the given repositories under their content
+// version, then makes it the version served to the other instances and
+// invalidates the previous one. The version is left untouched when any
+// entry could not be written, and nothing is written when it is already
+// the one served, its keys being immutable.
+func storeReposInCache(ctx context.Context, repos []structs.Repo, version string) error {
+	var previous string
+	if data, err := cacheManager.Get(ctx, contentVersionKey); err == nil {
+		_ = json.Unmarshal(data, &previous)
+	}
+
+	if previous == version {
+		return nil
+	}
+
+	var failures int
+	ids := make([]string, 0, len(repos))
+
+	for _, repo := range repos {
+		ids = append(ids, repo.Id)
+
+		for _, err := range storeRepoInCache(ctx, repo, version) {
+			log.Printf("(cache): %v\n", err)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d entries could not be cached", failures)
+	}
+
+	err := setCacheEntry(ctx, reposIndexKey(version), ids, versionTag(version))
+	if err != nil {
+		return err
+	}
+
+	err = setCacheEntry(ctx, contentVersionKey, version)
+	if err != nil {
+		return err
+	}
+
+	// instances still reading the previous version fail and retry with
+	// the new one
+	if previous != "" {
+		err := cacheManager.Invalidate(ctx, store.WithInvalidateTags([]string{versionTag(previous)}))
+		if err != nil {
+			log.Printf("(cache): Unable to invalidate content version %s: %v\n", previous, err)
+		}
+	}
+
+	return nil
+}
+
+// storeRepoInCache writes the keys of a single repository, returning the
+// errors of the entries which could not be written.
+func storeRepoInCache(ctx context.Context, repo structs.Repo, version string) []error {
+	var errs []error
+
+	meta := cachedRepo{Repo: repo}
+	meta.Repo.Articles = nil
+	meta.Repo.ArticlesGrouped = nil
+	meta.Repo.History = nil
+	for lang := range repo.ArticlesGrouped {
+		meta.ArticleLanguages = append(meta.ArticleLanguages, lang)
+	}
+
+	tag := versionTag(version)
+	err := setCacheEntry(ctx, repoMetaKey(version, repo.Id), meta, tag)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for lang, articles := range repo.ArticlesGrouped {
+		slugs := make([]string, 0, len(articles))
+		for _, article := range articles {
+			slugs = append(slugs, article.Slug)
+
+			err := setCacheEntry(ctx, repoArticleKey(version, repo.Id, lang, article.Slug), cachedArticle{
+				Article: article,
+				History: repo.History[article.Path],
+			}, tag)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		err := setCacheEntry(ctx, repoListKey(version, repo.Id, lang), slugs, tag)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// loadReposFromCache rebuilds the repositories stored by storeReposInCache
+// under the given content version, failing if any of their keys is missing.
+func loadReposFromCache(ctx context.Context, version string) ([]structs.Repo, error) {
+	var ids []string
+	err := getCacheEntry(ctx, reposIndexKey(version), &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make([]structs.Repo, 0, len(ids))
+	for _, id := range ids {
+		var meta cachedRepo
+		err := getCacheEntry(ctx, repoMetaKey(version, id), &meta)
+		if err != nil {
+			return nil, err
+		}
+
+		repo := meta.Repo
+		repo.Articles = make(map[string]structs.Article)
+		repo.ArticlesGrouped = make(map[string][]structs.Article, len(meta.ArticleLanguages))
+		repo.History = make(map[string][]structs.ArticleCommit)
+
+		for _, lang := range meta.ArticleLanguages {
+			var slugs []string
+			err := getCacheEntry(ctx, repoListKey(version, id, lang), &slugs)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, slug := range slugs {
+				var entry cachedArticle
+				err := getCacheEntry(ctx, repoArticleKey(version, id, lang, slug), &entry)
+				if err != nil {
+					return nil, err
+				}
+
+				repo.Articles[entry.Article.Path] = entry.Article
+				repo.ArticlesGrouped[lang] = append(repo.ArticlesGrouped[lang], entry.Article)
+				if len(entry.History) > 0 {
+					repo.History[entry.Article.Path] = entry.History
+				}
+			}
+		}
+
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
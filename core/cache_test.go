@@ -0,0 +1,61 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+)
+
+func TestLocalTierSkipsContentVersion(t *testing.T) {
+	previousManager, previousLocal := cacheManager, localCache
+	t.Cleanup(func() {
+		cacheManager, localCache = previousManager, previousLocal
+	})
+
+	local, err := NewGoCache()
+	if err != nil {
+		t.Fatalf("NewGoCache() failed: %v", err)
+	}
+	shared, err := NewGoCache()
+	if err != nil {
+		t.Fatalf("NewGoCache() failed: %v", err)
+	}
+
+	localCache = &localTierCache{SetterCacheInterface: local, ttl: time.Minute}
+	cacheManager = cache.NewChain[[]byte](localCache, shared)
+
+	ctx := context.Background()
+	for _, key := range []string{contentVersionKey, reposIndexKey("v1")} {
+		err := setCacheEntry(ctx, key, "v1")
+		if err != nil {
+			t.Fatalf("setCacheEntry(%s) failed: %v", key, err)
+		}
+
+		var value string
+		err = getCacheEntry(ctx, key, &value)
+		if err != nil || value != "v1" {
+			t.Fatalf("getCacheEntry(%s) = %q, %v, want v1", key, value, err)
+		}
+	}
+
+	// let the chain write the entries read back to the local tier
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := local.Get(ctx, contentVersionKey); err == nil {
+		t.Errorf("%s was kept in the local tier", contentVersionKey)
+	}
+	if _, err := local.Get(ctx, reposIndexKey("v1")); err != nil {
+		t.Errorf("%s was not kept in the local tier: %v", reposIndexKey("v1"), err)
+	}
+}
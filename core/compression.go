@@ -0,0 +1,148 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingZstd = "zstd"
+	encodingGzip = "gzip"
+
+	// bodies smaller than this are not worth the compression overhead
+	compressionMinSize = 1024
+)
+
+// supportedEncodings lists the content codings Chronos produces, by order of
+// preference when a client accepts several of them equally.
+var supportedEncodings = []string{encodingZstd, encodingGzip}
+
+var (
+	zstdEncoder, _     = zstd.NewWriter(nil)
+	zstdBestEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+)
+
+// negotiateEncoding picks the content coding to use for a response from the
+// Accept-Encoding header of the request, an empty string meaning identity.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(name) != "q" {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+
+		accepted[coding] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, encoding := range supportedEncodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressBody encodes body with the given content coding. The best level
+// is slower and meant for bodies compressed once and served many times.
+func compressBody(encoding string, body []byte, best bool) ([]byte, error) {
+	switch encoding {
+	case encodingZstd:
+		if best {
+			return zstdBestEncoder.EncodeAll(body, nil), nil
+		}
+
+		return zstdEncoder.EncodeAll(body, nil), nil
+	case encodingGzip:
+		level := gzip.DefaultCompression
+		if best {
+			level = gzip.BestCompression
+		}
+
+		var buf bytes.Buffer
+		writer, err := gzip.NewWriterLevel(&buf, level)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = writer.Write(body)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writer.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	return body, nil
+}
+
+// encoded returns the response body encoded with the given content coding,
+// or the identity body if it should not or could not be compressed.
+// Memoized responses keep their variants, so that they are compressed only
+// once per content version.
+func (r *renderedResponse) encoded(encoding string) ([]byte, string) {
+	if encoding == "" || len(r.body) < compressionMinSize {
+		return r.body, ""
+	}
+
+	if r.memoized {
+		if variant, ok := r.variants.Load(encoding); ok {
+			return variant.([]byte), encoding
+		}
+	}
+
+	variant, err := compressBody(encoding, r.body, r.memoized)
+	if err != nil {
+		log.Printf("(compression): Unable to encode response with %s: %v\n", encoding, err)
+		return r.body, ""
+	}
+
+	if r.memoized {
+		stored, _ := r.variants.LoadOrStore(encoding, variant)
+		variant = stored.([]byte)
+	}
+
+	return variant, encoding
+}
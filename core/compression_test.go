@@ -0,0 +1,141 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"br", ""},
+		{"gzip", encodingGzip},
+		{"GZIP", encodingGzip},
+		{"zstd", encodingZstd},
+		{"gzip, deflate, br, zstd", encodingZstd},
+		{"gzip;q=1.0, zstd;q=0.5", encodingGzip},
+		{"zstd;q=0, gzip", encodingGzip},
+		{"gzip;q=0", ""},
+		{"*", encodingZstd},
+		{"*;q=0.5, gzip", encodingGzip},
+		{"*, zstd;q=0", encodingGzip},
+	}
+
+	for _, test := range tests {
+		if got := negotiateEncoding(test.acceptEncoding); got != test.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", test.acceptEncoding, got, test.want)
+		}
+	}
+}
+
+// decodeBody returns the body of a response decoded with its content
+// coding.
+func decodeBody(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+
+	switch encoding {
+	case encodingZstd:
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer decoder.Close()
+
+		decoded, err := decoder.DecodeAll(body, nil)
+		if err != nil {
+			t.Fatalf("invalid zstd body: %v", err)
+		}
+		return decoded
+	case encodingGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+
+		decoded, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		return decoded
+	}
+
+	return body
+}
+
+func TestWriteRenderedEncoding(t *testing.T) {
+	large := []byte(`{"Body":"` + strings.Repeat("chronos ", compressionMinSize) + `"}`)
+	small := []byte(`{"Id":"docs"}`)
+
+	tests := []struct {
+		name           string
+		body           []byte
+		acceptEncoding string
+		want           string
+	}{
+		{"zstd", large, "gzip, zstd", encodingZstd},
+		{"gzip", large, "gzip", encodingGzip},
+		{"identity", large, "", ""},
+		{"small body", small, "gzip, zstd", ""},
+	}
+
+	for _, test := range tests {
+		response := newRenderedResponse(test.body, "application/json", time.Time{})
+		response.memoized = true
+
+		// the second request is served the memoized variant
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			r.Header.Set("Accept-Encoding", test.acceptEncoding)
+			w := httptest.NewRecorder()
+
+			writeRendered(w, r, "repo", response)
+			if encoding := w.Header().Get("Content-Encoding"); encoding != test.want {
+				t.Errorf("%s: Content-Encoding = %q, want %q", test.name, encoding, test.want)
+			}
+			if etag := w.Header().Get("ETag"); etag != response.variantETag(test.want) {
+				t.Errorf("%s: ETag = %s, want %s", test.name, etag, response.variantETag(test.want))
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("%s: Vary = %q, want Accept-Encoding", test.name, vary)
+			}
+			if !bytes.Equal(decodeBody(t, test.want, w.Body.Bytes()), test.body) {
+				t.Errorf("%s: the decoded body differs from the response", test.name)
+			}
+		}
+	}
+
+	// the identity and the encoded entity tags both validate a cached copy
+	response := newRenderedResponse(large, "application/json", time.Time{})
+	for _, etag := range []string{response.etag, response.variantETag(encodingGzip)} {
+		r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		r.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+
+		writeRendered(w, r, "repo", response)
+		if w.Code != http.StatusNotModified || w.Header().Get("ETag") != response.variantETag(encodingGzip) {
+			t.Errorf("If-None-Match %s: status = %d, ETag = %s, want 304 with the gzip ETag", etag, w.Code, w.Header().Get("ETag"))
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/vanilla-os/Chronos/settings"
+)
+
+const (
+	syncLockKey        = "chronos:sync-lock"
+	syncLockTTL        = 30 * time.Minute
+	versionsChannel    = "chronos:content-versions"
+	contentVersionKey  = "repos:version"
+	syncLockRetryDelay = 5 * time.Second
+)
+
+// Coordinator lets several Chronos instances sharing a cache backend agree
+// on which one synchronizes the repositories, and announces the content
+// versions it produces to the others.
+type Coordinator interface {
+	// TryLock acquires the synchronization lock without waiting. The
+	// returned function releases it and must be called once done.
+	TryLock(ctx context.Context) (release func(), ok bool, err error)
+	// Publish announces a new content version.
+	Publish(ctx context.Context, version string) error
+	// Subscribe returns the content versions announced from now on, until
+	// ctx is done.
+	Subscribe(ctx context.Context) (<-chan string, error)
+}
+
+var coordinator Coordinator = NewLocalCoordinator()
+
+// LocalCoordinator is an in-process Coordinator, used when Chronos runs as
+// a single instance. Instances sharing a LocalCoordinator behave as
+// replicas sharing a Redis server.
+type LocalCoordinator struct {
+	lock        sync.Mutex
+	mu          sync.Mutex
+	subscribers []chan string
+}
+
+// NewLocalCoordinator creates a new in-process coordinator.
+func NewLocalCoordinator() *LocalCoordinator {
+	return &LocalCoordinator{}
+}
+
+func (c *LocalCoordinator) TryLock(ctx context.Context) (func(), bool, error) {
+	if !c.lock.TryLock() {
+		return nil, false, nil
+	}
+
+	return c.lock.Unlock, true, nil
+}
+
+func (c *LocalCoordinator) Publish(ctx context.Context, version string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, subscriber := range c.subscribers {
+		select {
+		case subscriber <- version:
+		default: // slow subscribers only need the latest version
+		}
+	}
+
+	return nil
+}
+
+func (c *LocalCoordinator) Subscribe(ctx context.Context) (<-chan string, error) {
+	versions := make(chan string, 1)
+
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, versions)
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, subscriber := range c.subscribers {
+			if subscriber == versions {
+				c.subscribers = append(c.subscribers[:i], c.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(versions)
+	}()
+
+	return versions, nil
+}
+
+// RedisCoordinatorClient represents the Redis features used by the
+// RedisCoordinator. A go-redis/redis client is adapted to it by
+// NewRedisCoordinatorClient.
+type RedisCoordinatorClient interface {
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd
+	Publish(ctx context.Context, channel string, message any) *redis.IntCmd
+	// Subscribe returns the payloads of the messages published on channel
+	// once subscribed, until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// redisCoordinatorClient adapts a go-redis/redis client to the
+// RedisCoordinatorClient interface.
+type redisCoordinatorClient struct {
+	*redis.Client
+}
+
+// NewRedisCoordinatorClient adapts a go-redis/redis client for the
+// RedisCoordinator.
+func NewRedisCoordinatorClient(client *redis.Client) RedisCoordinatorClient {
+	return redisCoordinatorClient{client}
+}
+
+func (c redisCoordinatorClient) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := c.Client.Subscribe(ctx, channel)
+
+	// wait for the subscription to be confirmed, so no message published
+	// after Subscribe returns can be missed
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("unable to subscribe to %s: %w", channel, err)
+	}
+
+	payloads := make(chan string)
+	go func() {
+		defer close(payloads)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				select {
+				case payloads <- message.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return payloads, nil
+}
+
+// releaseLockScript deletes the lock only if it is still held by the
+// caller, so an expired lock taken over by another instance is left alone.
+const releaseLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+// RedisCoordinator is a Coordinator based on a Redis lock and a pub/sub
+// channel.
+type RedisCoordinator struct {
+	client RedisCoordinatorClient
+}
+
+// NewRedisCoordinator creates a new coordinator on top of a Redis client.
+func NewRedisCoordinator(client RedisCoordinatorClient) *RedisCoordinator {
+	return &RedisCoordinator{
+		client: client,
+	}
+}
+
+func (c *RedisCoordinator) TryLock(ctx context.Context) (func(), bool, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, false, err
+	}
+	value := hex.EncodeToString(token)
+
+	ok, err := c.client.SetNX(ctx, syncLockKey, value, syncLockTTL).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	release := func() {
+		err := c.client.Eval(context.Background(), releaseLockScript, []string{syncLockKey}, value).Err()
+		if err != nil {
+			log.Printf("(coordinator): Unable to release the sync lock: %v\n", err)
+		}
+	}
+
+	return release, true, nil
+}
+
+func (c *RedisCoordinator) Publish(ctx context.Context, version string) error {
+	return c.client.Publish(ctx, versionsChannel, version).Err()
+}
+
+func (c *RedisCoordinator) Subscribe(ctx context.Context) (<-chan string, error) {
+	payloads, err := c.client.Subscribe(ctx, versionsChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(chan string, 1)
+	go func() {
+		defer close(versions)
+
+		for payload := range payloads {
+			select {
+			case versions <- payload:
+			default: // slow subscribers only need the latest version
+			}
+		}
+	}()
+
+	return versions, nil
+}
+
+// InitCoordinator sets up the coordination between instances, which is
+// only enabled for the redis cache backend.
+func InitCoordinator() error {
+	if !settings.Cnf.RedisCoordination {
+		return nil
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redisCoordination requires the redis cache backend")
+	}
+
+	coordinator = NewRedisCoordinator(NewRedisCoordinatorClient(redisClient))
+	log.Println("(coordinator): Coordinating with other instances over Redis")
+
+	return nil
+}
+
+// watchContentVersions reloads the repositories from the shared cache each
+// time another instance announces a new content version.
+func watchContentVersions(ctx context.Context) error {
+	versions, err := coordinator.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for version := range versions {
+			if s := getSnapshot(); s != nil && s.version == version {
+				continue
+			}
+
+			log.Printf("(coordinator): New content version announced: %s\n", version)
+
+			// the local tier holds entries of the previous version
+			clearLocalCache(ctx)
+
+			err := loadSharedRepos(ctx)
+			recordReload(err)
+			if err != nil {
+				log.Printf("(coordinator): Unable to load content version %s: %v\n", version, err)
+			}
+		}
+	}()
+
+	return nil
+}
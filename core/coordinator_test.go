@@ -0,0 +1,210 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// fakeRedis is an in-process stand-in for the Redis features used by the
+// RedisCoordinator.
+type fakeRedis struct {
+	mu          sync.Mutex
+	values      map[string]string
+	subscribers map[string][]chan string
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		values:      make(map[string]string),
+		subscribers: make(map[string][]chan string),
+	}
+}
+
+func (f *fakeRedis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.values[key] = value.(string)
+
+	return redis.NewBoolResult(true, nil)
+}
+
+// Eval only runs releaseLockScript.
+func (f *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if script != releaseLockScript {
+		return redis.NewCmdResult(nil, redis.Nil)
+	}
+	if f.values[keys[0]] != args[0] {
+		return redis.NewCmdResult(int64(0), nil)
+	}
+	delete(f.values, keys[0])
+
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func (f *fakeRedis) Publish(ctx context.Context, channel string, message any) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, subscriber := range f.subscribers[channel] {
+		subscriber <- message.(string)
+	}
+
+	return redis.NewIntResult(int64(len(f.subscribers[channel])), nil)
+}
+
+func (f *fakeRedis) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	payloads := make(chan string, 16)
+
+	f.mu.Lock()
+	f.subscribers[channel] = append(f.subscribers[channel], payloads)
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		subscribers := f.subscribers[channel]
+		for i, subscriber := range subscribers {
+			if subscriber == payloads {
+				f.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(payloads)
+	}()
+
+	return payloads, nil
+}
+
+func TestRedisCoordinatorLock(t *testing.T) {
+	ctx := context.Background()
+	replica1 := NewRedisCoordinator(newFakeRedis())
+	replica2 := &RedisCoordinator{client: replica1.client}
+
+	release, ok, err := replica1.TryLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("TryLock() = %v, %v, want the lock", ok, err)
+	}
+
+	_, ok, err = replica2.TryLock(ctx)
+	if err != nil || ok {
+		t.Fatalf("TryLock() while locked = %v, %v, want no lock", ok, err)
+	}
+
+	release()
+
+	release, ok, err = replica2.TryLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("TryLock() after release = %v, %v, want the lock", ok, err)
+	}
+	release()
+}
+
+func TestRedisCoordinatorPublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewRedisCoordinator(newFakeRedis())
+	versions, err := c.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+
+	err = c.Publish(ctx, "v1")
+	if err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+
+	select {
+	case version := <-versions:
+		if version != "v1" {
+			t.Errorf("received version %q, want v1", version)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no version received")
+	}
+
+	cancel()
+	for range versions {
+	}
+}
+
+func TestRedisCoordinatorReload(t *testing.T) {
+	previousManager, previousCoordinator := cacheManager, coordinator
+	t.Cleanup(func() {
+		cacheManager, coordinator = previousManager, previousCoordinator
+		currentSnapshot.Store(nil)
+	})
+
+	var err error
+	cacheManager, err = NewGoCache()
+	if err != nil {
+		t.Fatalf("NewGoCache() failed: %v", err)
+	}
+	coordinator = NewRedisCoordinator(newFakeRedis())
+	currentSnapshot.Store(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = watchContentVersions(ctx)
+	if err != nil {
+		t.Fatalf("watchContentVersions() failed: %v", err)
+	}
+
+	for _, version := range []string{"v1", "v2"} {
+		article := structs.Article{Slug: "intro", Path: "docs/en/intro.md", Language: "en", Title: version}
+		repos := []structs.Repo{{
+			Id:              "docs",
+			Languages:       []string{"en"},
+			Articles:        map[string]structs.Article{article.Path: article},
+			ArticlesGrouped: map[string][]structs.Article{"en": {article}},
+		}}
+
+		err := storeReposInCache(ctx, repos, version)
+		if err != nil {
+			t.Fatalf("storeReposInCache(%s) failed: %v", version, err)
+		}
+		announceContentVersion(ctx, version)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for s := getSnapshot(); s == nil || s.version != version; s = getSnapshot() {
+			if time.Now().After(deadline) {
+				t.Fatalf("content version %s was not reloaded", version)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		loaded := getSnapshot().getArticle("docs", "en", "intro")
+		if loaded == nil || loaded.Title != version {
+			t.Fatalf("reloaded article = %+v, want the one of %s", loaded, version)
+		}
+	}
+
+	var ids []string
+	if err := getCacheEntry(ctx, reposIndexKey("v1"), &ids); err == nil {
+		t.Error("the keys of the previous content version were not invalidated")
+	}
+}
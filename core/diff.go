@@ -0,0 +1,307 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+var errNoGitHistory = errors.New("repo is not tracked by Git")
+
+// resolveCommit resolves a revision (hash, branch, tag, HEAD~n...) to a
+// commit, an empty revision means HEAD.
+func resolveCommit(r *git.Repository, rev string) (*object.Commit, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %s: %v", rev, err)
+	}
+
+	return r.CommitObject(*hash)
+}
+
+// resolveCommitAt returns the newest commit reachable from HEAD which was
+// committed at or before the given time, nil if there is none.
+func resolveCommitAt(r *git.Repository, at time.Time) (*object.Commit, error) {
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Git HEAD: %v", err)
+	}
+
+	iter, err := gitLog(r, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for {
+		c, err := iter.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk Git log: %v", err)
+		}
+
+		if !c.Committer.When.After(at) {
+			return c, nil
+		}
+	}
+}
+
+// parseSince parses a date in RFC 3339 or YYYY-MM-DD form.
+func parseSince(since string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		t, err := time.Parse(layout, since)
+		if err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// getArticleDiff returns the diff of the article source file between two
+// revisions of its repository.
+func getArticleDiff(repo structs.Repo, article structs.Article, fromRev string, toRev string) (structs.ArticleDiffResponse, error) {
+	response := structs.ArticleDiffResponse{
+		Language: article.Language,
+		Slug:     article.Slug,
+	}
+
+	r, root, err := openRepoGit(repo)
+	if err != nil {
+		return response, err
+	}
+	if r == nil {
+		return response, errNoGitHistory
+	}
+
+	from, err := resolveCommit(r, fromRev)
+	if err != nil {
+		return response, err
+	}
+
+	to, err := resolveCommit(r, toRev)
+	if err != nil {
+		return response, err
+	}
+
+	response.From = from.Hash.String()
+	response.To = to.Hash.String()
+	response.Status = "unchanged"
+	response.Chunks = []structs.DiffChunk{}
+
+	rel, err := gitRelPath(root, article.Path)
+	if err != nil {
+		return response, err
+	}
+
+	changes, err := diffCommits(from, to, path.Dir(rel))
+	if err != nil {
+		return response, err
+	}
+
+	for _, change := range changes {
+		if change.To.Name != path.Base(rel) && change.From.Name != path.Base(rel) {
+			continue
+		}
+
+		response.Status, err = changeStatus(change)
+		if err != nil {
+			return response, err
+		}
+
+		patch, err := change.Patch()
+		if err != nil {
+			return response, fmt.Errorf("failed to compute patch: %v", err)
+		}
+
+		response.Patch = patch.String()
+		for _, filePatch := range patch.FilePatches() {
+			for _, chunk := range filePatch.Chunks() {
+				response.Chunks = append(response.Chunks, structs.DiffChunk{
+					Type:    chunkType(chunk.Type()),
+					Content: chunk.Content(),
+				})
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// getRepoChanges returns the articles added, modified or deleted since the
+// given revision or date.
+func getRepoChanges(repo structs.Repo, since string) (structs.ChangesResponse, error) {
+	response := structs.ChangesResponse{
+		Since:   since,
+		Changes: []structs.ArticleChange{},
+	}
+
+	r, root, err := openRepoGit(repo)
+	if err != nil {
+		return response, err
+	}
+	if r == nil {
+		return response, errNoGitHistory
+	}
+
+	to, err := resolveCommit(r, "")
+	if err != nil {
+		return response, err
+	}
+
+	var from *object.Commit
+	if at, ok := parseSince(since); ok {
+		from, err = resolveCommitAt(r, at)
+	} else {
+		from, err = resolveCommit(r, since)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	response.To = to.Hash.String()
+	if from != nil {
+		response.From = from.Hash.String()
+	}
+
+	prefix, err := gitRelPath(root, filepath.Join(repo.Path, repo.RootPath))
+	if err != nil {
+		return response, err
+	}
+
+	changes, err := diffCommits(from, to, prefix)
+	if err != nil {
+		return response, err
+	}
+
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+
+		lang, slug, ok := articleFromTreePath(repo, name)
+		if !ok {
+			continue
+		}
+
+		status, err := changeStatus(change)
+		if err != nil {
+			return response, err
+		}
+
+		response.Changes = append(response.Changes, structs.ArticleChange{
+			Status:   status,
+			Language: lang,
+			Slug:     slug,
+			Path:     filepath.Join(repo.Path, repo.RootPath, filepath.FromSlash(name)),
+		})
+	}
+
+	return response, nil
+}
+
+// diffCommits returns the changes between the trees at prefix of two
+// commits, a nil from commit means an empty tree.
+func diffCommits(from *object.Commit, to *object.Commit, prefix string) (object.Changes, error) {
+	var fromTree, toTree *object.Tree
+	var err error
+
+	if from != nil {
+		fromTree, err = subTree(from, prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	toTree, err = subTree(to, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff Git trees: %v", err)
+	}
+
+	return changes, nil
+}
+
+// articleFromTreePath returns the language and slug of the article stored at
+// name, relative to the repo root path, following the layout expected by
+// loadArticlesFromRepo.
+func articleFromTreePath(repo structs.Repo, name string) (string, string, bool) {
+	if path.Ext(name) != ".md" {
+		return "", "", false
+	}
+
+	slug := strings.TrimSuffix(path.Base(name), ".md")
+	parts := strings.Split(name, "/")
+
+	switch {
+	case len(parts) == 2 && isValidLocale(parts[0]):
+		return parts[0], slug, true
+	case len(parts) == 1 && repo.FallbackEnabled:
+		lang := repo.FallbackLang
+		if lang == "" {
+			lang = "en"
+		}
+
+		return lang, slug, true
+	}
+
+	return "", "", false
+}
+
+func changeStatus(change *object.Change) (string, error) {
+	action, err := change.Action()
+	if err != nil {
+		return "", err
+	}
+
+	switch action {
+	case merkletrie.Insert:
+		return "added", nil
+	case merkletrie.Delete:
+		return "deleted", nil
+	default:
+		return "modified", nil
+	}
+}
+
+func chunkType(op diff.Operation) string {
+	switch op {
+	case diff.Add:
+		return "add"
+	case diff.Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
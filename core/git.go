@@ -10,81 +10,457 @@ package core
 */
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/vanilla-os/Chronos/settings"
 )
 
-func synGitRepo(repo string, force bool) error {
-	repoDir := reposDir + strings.ReplaceAll(repo, "/", "_")
+func synGitRepo(repo settings.ConfigRepo) error {
+	repoDir := gitRepoDir(repo)
 
 	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
 		os.Mkdir(repoDir, 0755)
 
-		_, err := git.PlainClone(repoDir, false, &git.CloneOptions{
-			URL: repo,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to clone Git repository: %v", err)
-		}
-	} else {
-		r, err := git.PlainOpen(repoDir)
-		if err != nil {
-			return fmt.Errorf("failed to open Git repository: %v", err)
-		}
+		return cloneGitRepo(repoDir, repo)
+	}
 
-		remotes, err := r.Remotes()
-		if err != nil {
-			return fmt.Errorf("failed to find Git remote settings: %v", err)
+	r, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return fmt.Errorf("failed to open Git repository: %v", err)
+	}
+
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("failed to find Git remote settings: %v", err)
+	}
+
+	origin := remote.Config().URLs[0]
+
+	if origin != repo.Url {
+		policy := settings.Cnf.GitRemoteChangePolicy
+		if policy == "" {
+			policy = "reclone"
 		}
 
-		origin := remotes[0].Config().URLs[0]
+		log.Printf("(git): Remote of %s changed from %s to %s, applying policy: %s\n", repo.Id, origin, repo.Url, policy)
 
-		if origin != repo {
-			var confirmation bool
+		switch policy {
+		case "reclone":
+			err := os.RemoveAll(repoDir)
+			if err != nil {
+				return fmt.Errorf("failed to remove old Git repository: %v", err)
+			}
 
-			if !force {
-				confirmation = askForConfirmation("The Git repository has been modified. Do you want to overwrite the current one?")
-			} else {
-				confirmation = true
+			return cloneGitRepo(repoDir, repo)
+		case "update-remote":
+			err := setGitRemoteUrl(r, repo.Url)
+			if err != nil {
+				return err
 			}
+		case "fail":
+			return fmt.Errorf("remote of %s changed from %s to %s", repo.Id, origin, repo.Url)
+		default:
+			return fmt.Errorf("unknown Git remote change policy: %s", policy)
+		}
+	}
 
-			if confirmation {
-				err := os.RemoveAll(repoDir)
-				if err != nil {
-					return fmt.Errorf("failed to remove old Git repository: %v", err)
-				}
+	return updateGitRepo(r, repoDir, repo)
+}
 
-				_, err = git.PlainClone(repoDir, false, &git.CloneOptions{
-					URL: repo,
-				})
+// setGitRemoteUrl points the origin remote to a new URL, keeping its fetch
+// refspecs.
+func setGitRemoteUrl(r *git.Repository, url string) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read Git config: %v", err)
+	}
 
-				if err != nil {
-					return fmt.Errorf("failed to clone Git repository: %v", err)
-				}
-			}
+	cfg.Remotes[git.DefaultRemoteName].URLs = []string{url}
+
+	err = r.SetConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to update Git remote: %v", err)
+	}
+
+	return nil
+}
+
+// gitRepoDir returns the checkout directory of a Git repository, named after
+// the repo ID when it is a safe directory name, or after a hash of its URL
+// otherwise.
+func gitRepoDir(repo settings.ConfigRepo) string {
+	if isSafeDirName(repo.Id) {
+		return filepath.Join(reposDir, repo.Id)
+	}
+
+	sum := sha256.Sum256([]byte(repo.Url))
+	return filepath.Join(reposDir, "url-"+hex.EncodeToString(sum[:8]))
+}
+
+// cleanupStaleCheckouts removes the Git checkouts in the repos directory
+// which do not belong to any configured repository anymore.
+func cleanupStaleCheckouts() error {
+	known := make(map[string]bool)
+	for _, repo := range settings.Cnf.GitRepos {
+		known[filepath.Base(gitRepoDir(repo))] = true
+	}
+
+	entries, err := os.ReadDir(reposDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || known[entry.Name()] {
+			continue
+		}
+
+		// only remove what looks like a checkout made by Chronos
+		stalePath := filepath.Join(reposDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(stalePath, git.GitDirName)); err != nil {
+			continue
 		}
 
-		w, err := r.Worktree()
+		log.Printf("(git): Removing stale checkout: %s\n", stalePath)
+		err := os.RemoveAll(stalePath)
 		if err != nil {
-			return fmt.Errorf("failed to open Git worktree: %v", err)
+			return fmt.Errorf("failed to remove stale checkout %s: %v", stalePath, err)
 		}
+	}
 
-		err = w.Pull(&git.PullOptions{})
+	return nil
+}
+
+// cloneGitRepo clones the repository into repoDir, honoring the configured
+// depth, branch, sparse checkout and submodules settings.
+func cloneGitRepo(repoDir string, repo settings.ConfigRepo) error {
+	if repo.Sparse && repo.Submodules {
+		log.Printf("(git): Submodules are not supported with sparse checkouts, ignoring them for %s\n", repo.Id)
+	}
+
+	r, err := git.PlainClone(repoDir, false, &git.CloneOptions{
+		URL:               repo.Url,
+		ReferenceName:     gitReferenceName(repo),
+		SingleBranch:      repo.SingleBranch,
+		Depth:             repo.Depth,
+		NoCheckout:        repo.Sparse,
+		RecurseSubmodules: gitSubmoduleRecursivity(repo),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clone Git repository: %v", err)
+	}
+
+	if !repo.Sparse {
+		return nil
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve Git HEAD: %v", err)
+	}
+
+	return sparseCheckout(r, repoDir, head.Hash(), repo)
+}
+
+// updateGitRepo brings an existing clone up to date with its remote. Sparse
+// and LFS clones are fetched and reset instead of pulled: a pull would
+// materialize the whole tree again, or refuse to run because of the LFS
+// files replacing their pointers in the worktree.
+func updateGitRepo(r *git.Repository, repoDir string, repo settings.ConfigRepo) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open Git worktree: %v", err)
+	}
+
+	if !repo.Sparse && !repo.Lfs {
+		err = w.Pull(&git.PullOptions{
+			ReferenceName:     gitReferenceName(repo),
+			SingleBranch:      repo.SingleBranch,
+			Depth:             repo.Depth,
+			RecurseSubmodules: gitSubmoduleRecursivity(repo),
+		})
 		if err != nil && err != git.NoErrAlreadyUpToDate {
 			return fmt.Errorf("failed to pull Git repository: %v", err)
 		}
+
+		return nil
+	}
+
+	err = r.Fetch(&git.FetchOptions{
+		Depth: repo.Depth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch Git repository: %v", err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve Git HEAD: %v", err)
+	}
+
+	// single-branch clones of the remote HEAD only track origin/HEAD
+	remoteRef, err := r.Reference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), true)
+	if err == plumbing.ErrReferenceNotFound {
+		remoteRef, err = r.Reference(plumbing.NewRemoteHEADReferenceName("origin"), true)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve remote branch %s: %v", head.Name().Short(), err)
+	}
+
+	if repo.Sparse {
+		return sparseCheckout(r, repoDir, remoteRef.Hash(), repo)
+	}
+
+	err = w.Reset(&git.ResetOptions{
+		Commit: remoteRef.Hash(),
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset Git worktree: %v", err)
+	}
+
+	if !repo.Submodules {
+		return nil
+	}
+
+	submodules, err := w.Submodules()
+	if err != nil {
+		return fmt.Errorf("failed to read Git submodules: %v", err)
+	}
+
+	err = submodules.Update(&git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update Git submodules: %v", err)
 	}
 
 	return nil
 }
 
-func detectGitChanges(repo string) (bool, error) {
-	repoDir := reposDir + strings.ReplaceAll(repo, "/", "_")
+// sparseCheckout writes the repository root path, as found in the given
+// commit, to the worktree and moves HEAD to that commit. Nothing outside the
+// root path is materialized; go-git's own sparse checkout is not used since
+// it does not reliably skip paths on updates.
+func sparseCheckout(r *git.Repository, repoDir string, commit plumbing.Hash, repo settings.ConfigRepo) error {
+	err := exportGitTree(r, commit, getRootPath(repo), repoDir)
+	if err != nil {
+		return err
+	}
 
-	r, err := git.PlainOpen(repoDir)
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open Git worktree: %v", err)
+	}
+
+	err = w.Reset(&git.ResetOptions{
+		Commit: commit,
+		Mode:   git.SoftReset,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move Git HEAD: %v", err)
+	}
+
+	return nil
+}
+
+// exportGitTree writes the files under path, as found in the given commit,
+// to the same path in dest, replacing what was there.
+func exportGitTree(r *git.Repository, commit plumbing.Hash, path string, dest string) error {
+	c, err := r.CommitObject(commit)
+	if err != nil {
+		return fmt.Errorf("failed to load Git commit %s: %v", commit, err)
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to load Git tree: %v", err)
+	}
+
+	subTree, err := tree.Tree(path)
+	if err != nil {
+		return fmt.Errorf("failed to find %s in Git tree: %v", path, err)
+	}
+
+	dest = filepath.Join(dest, path)
+	err = os.RemoveAll(dest)
+	if err != nil {
+		return fmt.Errorf("failed to clean %s: %v", dest, err)
+	}
+
+	err = subTree.Files().ForEach(func(f *object.File) error {
+		return writeGitFile(f, filepath.Join(dest, f.Name))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// checkoutPreviewBranch brings a branch of a Git repository up to date in
+// a bare clone of its own, so that previews never touch the checkout being
+// served, and exports its root path to a directory which is returned.
+func checkoutPreviewBranch(repo settings.ConfigRepo, branch string) (string, error) {
+	sum := sha256.Sum256([]byte(repo.Url + "\x00" + branch))
+	dir := filepath.Join(reposDir, previewDirName, hex.EncodeToString(sum[:8]))
+	gitDir := dir + ".git"
+	ref := plumbing.NewBranchReferenceName(branch)
+
+	r, err := git.PlainOpen(gitDir)
+	switch err {
+	case git.ErrRepositoryNotExists:
+		r, err = git.PlainClone(gitDir, true, &git.CloneOptions{
+			URL:           repo.Url,
+			ReferenceName: ref,
+			SingleBranch:  true,
+			Depth:         repo.Depth,
+		})
+		if err != nil {
+			os.RemoveAll(gitDir)
+			return "", fmt.Errorf("failed to clone branch %s: %v", branch, err)
+		}
+	case nil:
+		err = r.Fetch(&git.FetchOptions{
+			RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))},
+			Depth:    repo.Depth,
+			Force:    true,
+		})
+		if errors.Is(err, git.NoMatchingRefSpecError{}) {
+			removePreviewCheckout(gitDir)
+			return "", fmt.Errorf("branch %s does not exist anymore", branch)
+		}
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return "", fmt.Errorf("failed to fetch branch %s: %v", branch, err)
+		}
+	default:
+		return "", fmt.Errorf("failed to open Git repository: %v", err)
+	}
+
+	head, err := r.Reference(ref, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve branch %s: %v", branch, err)
+	}
+
+	err = exportGitTree(r, head.Hash(), getRootPath(repo), dir)
+	if err != nil {
+		return "", err
+	}
+
+	// the modification time of the clone tells when it was last previewed
+	now := time.Now()
+	err = os.Chtimes(gitDir, now, now)
+	if err != nil {
+		log.Printf("(git): Unable to mark preview checkout %s as used: %v\n", gitDir, err)
+	}
+
+	return dir, nil
+}
+
+// cleanupPreviewCheckouts removes the branches checked out for previews
+// which were not previewed for previewIdleTTL.
+func cleanupPreviewCheckouts() error {
+	pruneBranchPreviews()
+
+	previewDir := filepath.Join(reposDir, previewDirName)
+	entries, err := os.ReadDir(previewDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < previewIdleTTL {
+			continue
+		}
+
+		removePreviewCheckout(filepath.Join(previewDir, entry.Name()))
+	}
+
+	return nil
+}
+
+// removePreviewCheckout removes the bare clone of a previewed branch along
+// with the directory its root path is exported to.
+func removePreviewCheckout(gitDir string) {
+	log.Printf("(git): Removing preview checkout: %s\n", gitDir)
+
+	for _, path := range []string{gitDir, strings.TrimSuffix(gitDir, ".git")} {
+		err := os.RemoveAll(path)
+		if err != nil {
+			log.Printf("(git): Unable to remove preview checkout %s: %v\n", path, err)
+		}
+	}
+}
+
+// writeGitFile writes a file from a Git tree to path, creating the parent
+// directories as needed.
+func writeGitFile(f *object.File, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	contents, err := f.Contents()
+	if err != nil {
+		return err
+	}
+
+	if f.Mode == filemode.Symlink {
+		return os.Symlink(contents, path)
+	}
+
+	mode, err := f.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(contents), mode.Perm())
+}
+
+// gitReferenceName returns the reference to clone and pull, an empty value
+// means the remote HEAD.
+func gitReferenceName(repo settings.ConfigRepo) plumbing.ReferenceName {
+	if repo.Branch == "" {
+		return ""
+	}
+
+	return plumbing.NewBranchReferenceName(repo.Branch)
+}
+
+// gitSubmoduleRecursivity returns how deep submodules are checked out,
+// sparse checkouts never include them.
+func gitSubmoduleRecursivity(repo settings.ConfigRepo) git.SubmoduleRescursivity {
+	if !repo.Submodules || repo.Sparse {
+		return git.NoRecurseSubmodules
+	}
+
+	return git.DefaultSubmoduleRecursionDepth
+}
+
+func detectGitChanges(repo settings.ConfigRepo) (bool, error) {
+	r, err := git.PlainOpen(gitRepoDir(repo))
 	if err != nil {
 		return false, fmt.Errorf("failed to open Git repository: %v", err)
 	}
@@ -0,0 +1,122 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// HandleCacheStats handles requests to /admin/cache.
+func HandleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminRequest(w, r) {
+		return
+	}
+
+	writeAdminResponse(w, getCacheStats(r.Context()))
+}
+
+// HandleCachePurge handles requests to /admin/cache/purge, purging the
+// whole cache or, with the repo or pattern query parameters, a part of it,
+// then rebuilding the repositories.
+func HandleCachePurge(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminRequest(w, r) {
+		return
+	}
+
+	repoId := r.URL.Query().Get("repo")
+	pattern := r.URL.Query().Get("pattern")
+	if repoId != "" && pattern != "" {
+		http.Error(w, "repo and pattern are mutually exclusive", http.StatusBadRequest)
+		return
+	}
+
+	if repoId != "" {
+		if _, err := getRepo(repoId); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		http.Error(w, "invalid pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	purged, err := purgeCache(r.Context(), repoId, pattern)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminResponse(w, structs.CacheActionResponse{
+		Action:         "purge",
+		Purged:         purged,
+		ContentVersion: getSnapshot().version,
+	})
+}
+
+// HandleCacheWarmup handles requests to /admin/cache/warmup.
+func HandleCacheWarmup(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminRequest(w, r) {
+		return
+	}
+
+	version, err := warmupCache()
+	if err == errSyncInProgress {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminResponse(w, structs.CacheActionResponse{
+		Action:         "warmup",
+		ContentVersion: version,
+	})
+}
+
+// checkAdminRequest verifies the bearer token of a request to the admin
+// endpoints, which do not exist unless adminToken is configured.
+func checkAdminRequest(w http.ResponseWriter, r *http.Request) bool {
+	if settings.Cnf.AdminToken == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(settings.Cnf.AdminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func writeAdminResponse(w http.ResponseWriter, response any) {
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
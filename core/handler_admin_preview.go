@@ -0,0 +1,89 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// HandlePreviewToken handles requests to /admin/preview, issuing a preview
+// token valid for the ttl query parameter, for all the repositories or the
+// repo one, optionally from one of its branches.
+func HandlePreviewToken(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminRequest(w, r) {
+		return
+	}
+
+	if settings.Cnf.PreviewKey == "" {
+		http.Error(w, errPreviewDisabled.Error(), http.StatusNotFound)
+		return
+	}
+
+	ttl := previewTokenTTL
+	if value := r.URL.Query().Get("ttl"); value != "" {
+		var err error
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 || ttl > previewTokenMaxTTL {
+			http.Error(w, fmt.Sprintf("invalid ttl, must be a duration up to %s", previewTokenMaxTTL), http.StatusBadRequest)
+			return
+		}
+	}
+
+	claims := previewClaims{
+		Repo:    r.URL.Query().Get("repo"),
+		Branch:  r.URL.Query().Get("branch"),
+		Expires: time.Now().Add(ttl).Unix(),
+	}
+
+	if claims.Repo != "" {
+		if _, err := getRepo(claims.Repo); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	if claims.Branch != "" {
+		if claims.Repo == "" {
+			http.Error(w, "branch requires repo", http.StatusBadRequest)
+			return
+		}
+
+		if err := plumbing.NewBranchReferenceName(claims.Branch).Validate(); err != nil {
+			http.Error(w, "invalid branch: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// check out the branch now, so that errors are reported to the
+		// issuer and not to the reviewers
+		if _, err := getBranchPreview(claims.Repo, claims.Branch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	token, err := signPreviewToken(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminResponse(w, structs.PreviewTokenResponse{
+		Token:     token,
+		ExpiresAt: time.Unix(claims.Expires, 0).UTC(),
+		Repo:      claims.Repo,
+		Branch:    claims.Branch,
+	})
+}
@@ -10,11 +10,12 @@ package core
 */
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/vanilla-os/Chronos/structs"
 )
 
 func HandleArticle(w http.ResponseWriter, r *http.Request) {
@@ -28,23 +29,88 @@ func HandleArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	if lang == "" || !isValidLocale(lang) {
-		http.Redirect(w, r, fmt.Sprintf("/%s/articles/en/%s", repoId, slug), http.StatusFound)
+		redirectToLanguage(w, r, repo, "articles", slug)
+		return
 	}
+	lang = resolveLanguage(repo, lang)
 
-	result, ok := searchArticle(repoId, lang, slug)
+	// only exact matches are memoized, fuzzy ones depend on arbitrary input
+	filter := newArticleFilter(r, repoId)
+	var key string
+	result, ok := findArticleTranslation(s, filter, repo, lang, slug)
+	if ok {
+		// the alternates depend on the visibility of the translations
+		key = filter.memoKey(s, repoId, fmt.Sprintf("article:%s:%s:%s", repoId, lang, slug))
+	} else if !hasArticleTranslation(s, repo, lang, slug) {
+		result, ok = searchArticle(s, filter, repoId, lang, slug)
+	}
 	if !ok {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-
-	jsonData, err := json.Marshal(result)
+	w.Header().Set("Content-Language", canonicalLocale(result.Language))
+	// the response also depends on the other articles, through Alternates
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		result.Alternates = articleAlternates(s, filter, repo, &result)
+		return result, nil
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(jsonData)
+	writeRendered(w, r, "article", rendered)
+}
+
+// findArticleTranslation returns the article with the given slug in the
+// first language of the fallback chain of lang having it. Articles served in
+// place of a missing translation are flagged as such.
+func findArticleTranslation(s *snapshot, filter articleFilter, repo *structs.Repo, lang string, slug string) (structs.Article, bool) {
+	for i, candidate := range languageChain(lang) {
+		article := s.getArticle(repo.Id, resolveLanguage(repo, candidate), slug)
+		if article == nil || !filter.reachable(article) {
+			continue
+		}
+
+		result := *article
+		if i > 0 {
+			result.Fallback = true
+			result.RequestedLanguage = lang
+		}
+
+		return result, true
+	}
+
+	return structs.Article{}, false
+}
+
+// hasArticleTranslation reports whether an article with the given slug
+// exists in the fallback chain of lang, whether it is reachable or not.
+func hasArticleTranslation(s *snapshot, repo *structs.Repo, lang string, slug string) bool {
+	for _, candidate := range languageChain(lang) {
+		if s.getArticle(repo.Id, resolveLanguage(repo, candidate), slug) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// articleLastModified returns the time of the last change to an article,
+// falling back to the one of its repository when it has no Git history.
+func articleLastModified(s *snapshot, repoId string, article structs.Article) time.Time {
+	if !article.LastModified.IsZero() {
+		return article.LastModified
+	}
+
+	return s.getLastModified(repoId)
 }
@@ -0,0 +1,78 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleArticleDiff handles requests to /articles/{lang}/{slug}/diff.
+func HandleArticleDiff(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+	lang := vars["lang"]
+	slug := vars["slug"]
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" {
+		http.Error(w, "missing from revision", http.StatusBadRequest)
+		return
+	}
+
+	if repoId == "" || lang == "" || !isValidLocale(lang) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	lang = resolveLanguage(repo, lang)
+
+	article, ok := searchArticle(s, newArticleFilter(r, repoId), repoId, lang, slug)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	result, err := getArticleDiff(*repo, article, from, to)
+	if err == errNoGitHistory {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// revisions are arbitrary input, so diffs are validated but not memoized
+	lastModified := articleLastModified(s, repoId, article)
+	if r.URL.Query().Get("format") == "unified" {
+		rendered := newRenderedResponse([]byte(result.Patch), "text/x-diff; charset=utf-8", lastModified)
+		writeRendered(w, r, "diff", rendered)
+		return
+	}
+
+	rendered, err := renderJSON(s, "", lastModified, func() (any, error) {
+		return result, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "diff", rendered)
+}
@@ -0,0 +1,69 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// HandleArticleHistory handles requests to /articles/{lang}/{slug}/history.
+func HandleArticleHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+	lang := vars["lang"]
+	slug := vars["slug"]
+
+	if repoId == "" || lang == "" || !isValidLocale(lang) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s := requestSnapshot(r)
+	filter := newArticleFilter(r, repoId)
+	repo, err := s.getRepo(repoId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	lang = resolveLanguage(repo, lang)
+
+	var key string
+	var article structs.Article
+	var ok bool
+	if exact := s.getArticle(repoId, lang, slug); exact != nil && filter.reachable(exact) {
+		key = fmt.Sprintf("history:%s:%s:%s", repoId, lang, slug)
+		article, ok = *exact, true
+	} else {
+		article, ok = searchArticle(s, filter, repoId, lang, slug)
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	rendered, err := renderJSON(s, key, articleLastModified(s, repoId, article), func() (any, error) {
+		history := repo.History[article.Path]
+		if history == nil {
+			history = []structs.ArticleCommit{}
+		}
+
+		return history, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "history", rendered)
+}
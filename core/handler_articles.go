@@ -10,9 +10,9 @@ package core
 */
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/gorilla/mux"
 	"github.com/vanilla-os/Chronos/structs"
@@ -29,39 +29,54 @@ func HandleArticles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if lang == "" || !isValidLocale(lang) {
-		http.Redirect(w, r, fmt.Sprintf("/%s/articles/en", repoId), http.StatusFound)
-	}
-
-	repo, err := getRepo(repoId)
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	if !repo.IsLangSupported(lang) {
-		http.Redirect(w, r, fmt.Sprintf("/%s/articles/en", repoId), http.StatusFound)
+	lang = findLanguage(repo.Languages, lang)
+	if lang == "" {
+		redirectToLanguage(w, r, repo, "articles", "")
+		return
+	}
+
+	query, err := parseArticlesQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	articles := repo.ArticlesGrouped[lang]
-	tags := getTags(articles)
-	response := structs.ArticlesResponse{
-		Title:         repo.Id,
-		SupportedLang: repo.Languages,
-		Tags:          tags,
-		Articles:      articles,
-		Stories:       repo.Stories,
+	// only the whole list is memoized, the combinations of the query
+	// parameters are unbounded
+	filter := newArticleFilter(r, repoId)
+	var key string
+	if query.isDefault() {
+		key = filter.memoKey(s, repoId, fmt.Sprintf("articles:%s:%s", repoId, lang))
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		articles := filter.listedArticles(repo.ArticlesGrouped[lang])
+		page, err := query.articlesPage(r.URL, articles)
+		if err != nil {
+			return nil, err
+		}
 
-	jsonData, err := json.Marshal(response)
+		return structs.ArticlesResponse{
+			Title:         repo.Id,
+			SupportedLang: repo.Languages,
+			Tags:          getTags(articles),
+			Stories:       localizeStories(repo.Stories, lang),
+			ArticlesPage:  page,
+		}, nil
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), articlesQueryStatus(err))
 		return
 	}
 
-	w.Write(jsonData)
+	writeRendered(w, r, "articles", rendered)
 }
 
 func getTags(articles []structs.Article) []string {
@@ -76,6 +91,7 @@ func getTags(articles []structs.Article) []string {
 	for tag := range tags {
 		tagsList = append(tagsList, tag)
 	}
+	sort.Strings(tagsList)
 
 	return tagsList
 }
@@ -0,0 +1,67 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleChanges handles requests to /changes.
+func HandleChanges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		http.Error(w, "missing since revision or date", http.StatusBadRequest)
+		return
+	}
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	result, err := getRepoChanges(*repo, since)
+	if err == errNoGitHistory {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// changes to the articles which are not listed are left out, deleted
+	// articles are no longer known so they are kept
+	filter := newArticleFilter(r, repoId)
+	changes := result.Changes[:0]
+	for _, change := range result.Changes {
+		article := s.getArticle(repoId, change.Language, change.Slug)
+		if article == nil || filter.listed(article) {
+			changes = append(changes, change)
+		}
+	}
+	result.Changes = changes
+
+	rendered, err := renderJSON(s, "", filter.lastModified(s, repoId), func() (any, error) {
+		return result, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "changes", rendered)
+}
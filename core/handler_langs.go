@@ -10,28 +10,40 @@ package core
 */
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/vanilla-os/Chronos/structs"
 )
 
+// HandleLangs handles requests to /{repoId}/langs, listing the languages of
+// a repository along with their native names and text direction.
 func HandleLangs(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	repoId := vars["repoId"]
 
-	repo, err := getRepo(repoId)
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	jsonData, err := json.Marshal(repo.Languages)
+	rendered, err := renderJSON(s, "langs:"+repoId, s.getLastModified(repoId), func() (any, error) {
+		response := structs.LangsResponse{
+			SupportedLang: repo.Languages,
+			Languages:     make([]structs.Language, len(repo.Languages)),
+		}
+		for i, lang := range repo.Languages {
+			response.Languages[i] = newLanguage(lang)
+		}
+
+		return response, nil
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonData)
+	writeRendered(w, r, "langs", rendered)
 }
@@ -0,0 +1,31 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleLanguageRedirect returns a handler redirecting the language-less
+// route of a section, such as /{repoId}/articles, to the language
+// negotiated for the request.
+func HandleLanguageRedirect(section string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repo, err := requestSnapshot(r).getRepo(mux.Vars(r)["repoId"])
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		redirectToLanguage(w, r, repo, section, "")
+	}
+}
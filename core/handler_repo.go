@@ -24,13 +24,20 @@ func HandleRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := getRepo(repoId)
+	s := requestSnapshot(r)
+	_, err := s.getRepo(repoId)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status": "ok"}`))
+	rendered, err := renderJSON(s, "repo:"+repoId, s.getLastModified(repoId), func() (any, error) {
+		return map[string]string{"status": "ok"}, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "repo", rendered)
 }
@@ -1,12 +1,8 @@
 package core
 
 import (
-	"context"
-	"encoding/json"
 	"log"
 	"net/http"
-
-	"github.com/vanilla-os/Chronos/structs"
 )
 
 /*	License: GPLv3
@@ -19,26 +15,11 @@ import (
 */
 
 func HandleRepos(w http.ResponseWriter, r *http.Request) {
-	reposBytes, err := cacheManager.Get(context.Background(), "Repos")
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error": "unable to get Repos from cache"}`))
-		log.Printf("unable to get Repos from cache: %s", err.Error())
-		return
-	}
-	if reposBytes == nil {
+	current := requestSnapshot(r)
+	if current == nil {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(`{"error": "Repos not found"}`))
-		log.Printf("Repos not available in cache, this might be a backend issue")
-		return
-	}
-
-	var repos []structs.Repo
-	err = json.Unmarshal(reposBytes, &repos)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error": "unable to unmarshal Repos"}`))
-		log.Printf("unable to unmarshal Repos: %s", err.Error())
+		log.Printf("Repos not loaded yet")
 		return
 	}
 
@@ -48,25 +29,37 @@ func HandleRepos(w http.ResponseWriter, r *http.Request) {
 		Languages       []string `json:"Languages"`
 		FallbackLang    string   `json:"FallbackLang"`
 		FallbackEnabled bool     `json:"FallbackEnabled"`
+
+		UnresolvedLfsFiles []string `json:"UnresolvedLfsFiles,omitempty"`
 	}
-	response := make([]repoResponse, len(repos))
-	for i, repo := range repos {
-		response[i] = repoResponse{
-			Id:              repo.Id,
-			Count:           len(repo.Articles),
-			Languages:       repo.Languages,
-			FallbackLang:    repo.FallbackLang,
-			FallbackEnabled: repo.FallbackEnabled,
+	filter := newArticleFilter(r, "")
+	rendered, err := renderJSON(current, filter.memoKey(current, "", "repos"), filter.lastModified(current, ""), func() (any, error) {
+		response := make([]repoResponse, len(current.repos))
+		for i, repo := range current.repos {
+			var count int
+			for _, article := range repo.Articles {
+				if filter.listed(&article) {
+					count++
+				}
+			}
+
+			response[i] = repoResponse{
+				Id:              repo.Id,
+				Count:           count,
+				Languages:       repo.Languages,
+				FallbackLang:    repo.FallbackLang,
+				FallbackEnabled: repo.FallbackEnabled,
+
+				UnresolvedLfsFiles: repo.UnresolvedLfsFiles,
+			}
 		}
-	}
 
-	responseBytes, err := json.Marshal(response)
+		return response, nil
+	})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(responseBytes)
+	writeRendered(w, r, "repos", rendered)
 }
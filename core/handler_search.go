@@ -10,8 +10,6 @@ package core
 */
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -34,26 +32,27 @@ func HandleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if lang == "" || !isValidLocale(lang) {
-		fmt.Println("Lang not found, redirecting to en")
-		http.Redirect(w, r, "/articles/"+repoId+"en/", http.StatusFound)
-	}
-
-	repo, err := getRepo(repoId)
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if lang == "" || !isValidLocale(lang) {
+		redirectToLanguage(w, r, repo, "search", "")
+		return
+	}
+	lang = resolveLanguage(repo, lang)
 
-	results := searchArticles(repo.Id, lang, query)
-	jsonData, err := json.Marshal(results)
+	filter := newArticleFilter(r, repoId)
+	rendered, err := renderJSON(s, "", filter.lastModified(s, repoId), func() (any, error) {
+		return searchArticles(s, filter, repo.Id, lang, query), nil
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(jsonData)
-
+	writeRendered(w, r, "search", rendered)
 }
@@ -0,0 +1,102 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// HandleStories handles requests to /stories, listing the stories available
+// in a language with their chapters.
+func HandleStories(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+	lang := vars["lang"]
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err == nil {
+		lang = findLanguage(repo.Languages, lang)
+	}
+	if err != nil || lang == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	filter := newArticleFilter(r, repoId)
+	key := filter.memoKey(s, repoId, fmt.Sprintf("stories:%s:%s", repoId, lang))
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		stories := []structs.StoryResponse{}
+		for _, story := range repo.Stories {
+			if response := newStoryResponse(s, filter, repo, lang, story); response != nil {
+				stories = append(stories, *response)
+			}
+		}
+
+		sort.Slice(stories, func(i, j int) bool {
+			return stories[i].Id < stories[j].Id
+		})
+
+		return stories, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "stories", rendered)
+}
+
+// HandleStory handles requests to /stories/{storyId}.
+func HandleStory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+	lang := vars["lang"]
+	storyId := vars["storyId"]
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err == nil {
+		lang = findLanguage(repo.Languages, lang)
+	}
+	if err != nil || lang == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	story, ok := repo.Stories[storyId]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	filter := newArticleFilter(r, repoId)
+	response := newStoryResponse(s, filter, repo, lang, story)
+	if response == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	key := filter.memoKey(s, repoId, fmt.Sprintf("story:%s:%s:%s", repoId, lang, storyId))
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		return response, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "story", rendered)
+}
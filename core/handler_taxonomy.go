@@ -0,0 +1,128 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleTags handles requests to /tags/{lang}, listing the tags of the
+// articles in a language along with their number of articles.
+func HandleTags(w http.ResponseWriter, r *http.Request) {
+	handleTerms(w, r, tagKind)
+}
+
+// HandleTag handles requests to /tags/{lang}/{tag}, listing the articles of
+// a tag with the same options as the articles list.
+func HandleTag(w http.ResponseWriter, r *http.Request) {
+	handleTerm(w, r, tagKind)
+}
+
+// HandleAuthors handles requests to /authors/{lang}, listing the authors of
+// the articles in a language along with their number of articles.
+func HandleAuthors(w http.ResponseWriter, r *http.Request) {
+	handleTerms(w, r, authorKind)
+}
+
+// HandleAuthor handles requests to /authors/{lang}/{author}, listing the
+// articles of an author with the same options as the articles list.
+func HandleAuthor(w http.ResponseWriter, r *http.Request) {
+	handleTerm(w, r, authorKind)
+}
+
+// handleTerms lists the terms of a kind used by the articles in a language.
+func handleTerms(w http.ResponseWriter, r *http.Request, kind termKind) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+	lang := vars["lang"]
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err == nil {
+		lang = findLanguage(repo.Languages, lang)
+	}
+	if err != nil || lang == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	filter := newArticleFilter(r, repoId)
+	key := filter.memoKey(s, repoId, fmt.Sprintf("%ss:%s:%s", kind.name, repoId, lang))
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		ids, counts := countTerms(filter.listedArticles(repo.ArticlesGrouped[lang]), kind.terms)
+
+		summaries := make([]any, len(ids))
+		for i, id := range ids {
+			summaries[i] = kind.summary(repo, id, counts[id])
+		}
+
+		return summaries, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, kind.name+"s", rendered)
+}
+
+// handleTerm lists the articles in a language classified by a term.
+func handleTerm(w http.ResponseWriter, r *http.Request, kind termKind) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+	lang := vars["lang"]
+	id := vars[kind.name]
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err == nil {
+		lang = findLanguage(repo.Languages, lang)
+	}
+	if err != nil || lang == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	query, err := parseArticlesQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := newArticleFilter(r, repoId)
+	articles := articlesWithTerm(filter.listedArticles(repo.ArticlesGrouped[lang]), kind.terms, id)
+	if len(articles) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var key string
+	if query.isDefault() {
+		key = filter.memoKey(s, repoId, fmt.Sprintf("%s:%s:%s:%s", kind.name, repoId, lang, id))
+	}
+
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		page, err := query.articlesPage(r.URL, articles)
+		if err != nil {
+			return nil, err
+		}
+
+		return kind.response(kind.summary(repo, id, len(articles)), page), nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), articlesQueryStatus(err))
+		return
+	}
+
+	writeRendered(w, r, kind.name, rendered)
+}
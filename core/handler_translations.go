@@ -0,0 +1,54 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleTranslations handles requests to /{repoId}/translations, reporting
+// which articles of the source language are missing, up to date or outdated
+// in the other languages. The source language is the default one unless
+// given by the source query parameter.
+func HandleTranslations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repoId := vars["repoId"]
+
+	s := requestSnapshot(r)
+	repo, err := s.getRepo(repoId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	source := defaultLanguage(repo)
+	if value := r.URL.Query().Get("source"); value != "" {
+		source = findLanguage(repo.Languages, value)
+		if source == "" {
+			http.Error(w, "unsupported source language: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := newArticleFilter(r, repoId)
+	key := filter.memoKey(s, repoId, fmt.Sprintf("translations:%s:%s", repoId, source))
+	rendered, err := renderJSON(s, key, filter.lastModified(s, repoId), func() (any, error) {
+		return newTranslationsResponse(s, filter, repo, source), nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeRendered(w, r, "translations", rendered)
+}
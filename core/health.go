@@ -0,0 +1,79 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// reloadStatus records the outcome of the reloads of the repositories. A
+// failed reload leaves the previous snapshot in place, so the content
+// served gets older until a reload succeeds again.
+var reloadStatus struct {
+	sync.Mutex
+	lastAttempt time.Time
+	lastSuccess time.Time
+	lastError   error
+}
+
+// recordReload records the outcome of a reload of the repositories.
+func recordReload(err error) {
+	reloadStatus.Lock()
+	defer reloadStatus.Unlock()
+
+	reloadStatus.lastAttempt = time.Now()
+	reloadStatus.lastError = err
+	if err == nil {
+		reloadStatus.lastSuccess = reloadStatus.lastAttempt
+	}
+}
+
+// GetStatus reports the status of the server and the age of the content it
+// serves.
+func GetStatus(version string) structs.StatusResponse {
+	status := structs.StatusResponse{
+		Status:  "ok",
+		Version: version,
+	}
+
+	s := getSnapshot()
+	if s == nil {
+		status.Status = "loading"
+		return status
+	}
+
+	status.ContentVersion = s.version
+	status.ContentLoadedAt = s.createdAt
+
+	reloadStatus.Lock()
+	defer reloadStatus.Unlock()
+
+	// content loaded from a persistent cache has not been reloaded yet
+	freshSince := reloadStatus.lastSuccess
+	if freshSince.IsZero() {
+		freshSince = s.createdAt
+	}
+	status.ContentAge = int64(time.Since(freshSince).Seconds())
+
+	if !reloadStatus.lastAttempt.IsZero() {
+		lastAttempt := reloadStatus.lastAttempt.UTC().Truncate(time.Second)
+		status.LastReloadAt = &lastAttempt
+	}
+
+	if reloadStatus.lastError != nil {
+		status.Status = "stale"
+		status.LastReloadError = reloadStatus.lastError.Error()
+	}
+
+	return status
+}
@@ -0,0 +1,277 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// openRepoGit opens the Git repository containing the given repo, returning
+// also the absolute path of its worktree root. A nil repository is returned
+// when the repo is not tracked by Git.
+func openRepoGit(repo structs.Repo) (*git.Repository, string, error) {
+	// instances serving a shared cache may have no checkout at all, which
+	// must not be mistaken for a parent repository
+	path := repo.Path
+	if path == "" {
+		path = "."
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, "", nil
+	}
+
+	r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err == git.ErrRepositoryNotExists {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open Git repository: %v", err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open Git worktree: %v", err)
+	}
+
+	root, err := filepath.Abs(w.Filesystem.Root())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return r, root, nil
+}
+
+// gitRelPath returns path relative to the Git worktree root, slash separated
+// as in Git trees.
+func gitRelPath(root string, path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// loadRepoHistory walks the Git log of the repository once and returns the
+// commits which touched each article, keyed by article path, newest first.
+// Repos not tracked by Git have no history.
+func loadRepoHistory(repo structs.Repo) (map[string][]structs.ArticleCommit, error) {
+	r, root, err := openRepoGit(repo)
+	if err != nil || r == nil {
+		return nil, err
+	}
+
+	head, err := r.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, nil // empty repository
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Git HEAD: %v", err)
+	}
+
+	prefix, err := gitRelPath(root, filepath.Join(repo.Path, repo.RootPath))
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := gitLog(r, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	byGitPath := make(map[string][]structs.ArticleCommit)
+	err = iter.ForEach(func(c *object.Commit) error {
+		files, err := changedFiles(c, prefix)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			byGitPath[file] = append(byGitPath[file], newArticleCommit(c))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk Git log: %v", err)
+	}
+
+	history := make(map[string][]structs.ArticleCommit, len(repo.Articles))
+	for path := range repo.Articles {
+		rel, err := gitRelPath(root, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if commits, ok := byGitPath[rel]; ok {
+			history[path] = commits
+		}
+	}
+
+	return history, nil
+}
+
+// gitLog returns an iterator over the history of the given commit, newest
+// first. Shallow clones end with commits whose parents were never fetched,
+// the walk stops there instead of failing.
+func gitLog(r *git.Repository, from plumbing.Hash) (object.CommitIter, error) {
+	c, err := r.CommitObject(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Git log: %v", err)
+	}
+
+	shallows, err := r.Storer.Shallow()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Git shallow commits: %v", err)
+	}
+
+	var missing []plumbing.Hash
+	for _, hash := range shallows {
+		shallow, err := r.CommitObject(hash)
+		if err != nil {
+			continue
+		}
+
+		missing = append(missing, shallow.ParentHashes...)
+	}
+
+	return object.NewCommitIterCTime(c, nil, missing), nil
+}
+
+// changedFiles returns the paths under prefix which the commit changed with
+// respect to its parent. Merge commits are skipped, the changes they bring
+// are attributed to the merged commits themselves.
+func changedFiles(c *object.Commit, prefix string) ([]string, error) {
+	if c.NumParents() > 1 {
+		return nil, nil
+	}
+
+	to, err := subTree(c, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var from *object.Tree
+	if c.NumParents() == 1 {
+		parent, err := c.Parent(0)
+		if err == nil {
+			from, err = subTree(parent, prefix)
+		}
+		if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil, err
+		}
+	}
+
+	if from == nil && to == nil {
+		return nil, nil
+	}
+	if from != nil && to != nil && from.Hash == to.Hash {
+		return nil, nil
+	}
+
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff commit %s: %v", c.Hash, err)
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+
+		files = append(files, joinGitPath(prefix, name))
+	}
+
+	return files, nil
+}
+
+// subTree returns the tree at prefix in the commit, nil if it does not exist.
+func subTree(c *object.Commit, prefix string) (*object.Tree, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix == "." || prefix == "" {
+		return tree, nil
+	}
+
+	sub, err := tree.Tree(prefix)
+	if err == object.ErrDirectoryNotFound {
+		return nil, nil
+	}
+
+	return sub, err
+}
+
+// joinGitPath joins Git tree paths, treating "." as the tree root.
+func joinGitPath(prefix string, name string) string {
+	if prefix == "." || prefix == "" {
+		return name
+	}
+
+	return prefix + "/" + name
+}
+
+func newArticleCommit(c *object.Commit) structs.ArticleCommit {
+	return structs.ArticleCommit{
+		Hash:    c.Hash.String(),
+		Author:  c.Author.Name,
+		Email:   c.Author.Email,
+		Date:    c.Author.When,
+		Message: strings.TrimSpace(c.Message),
+	}
+}
+
+// applyArticleHistory populates the Git derived metadata of the articles
+// from the repository history.
+func applyArticleHistory(repo *structs.Repo) {
+	for path, article := range repo.Articles {
+		commits := repo.History[path]
+		if len(commits) == 0 {
+			continue
+		}
+
+		article.LastModified = commits[0].Date
+		article.CreatedAt = commits[len(commits)-1].Date
+
+		seen := make(map[string]bool)
+		article.Contributors = nil
+		for i := len(commits) - 1; i >= 0; i-- {
+			if seen[commits[i].Author] {
+				continue
+			}
+
+			seen[commits[i].Author] = true
+			article.Contributors = append(article.Contributors, commits[i].Author)
+		}
+
+		repo.Articles[path] = article
+	}
+}
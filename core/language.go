@@ -0,0 +1,264 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// rtlScripts are the scripts written from right to left.
+var rtlScripts = map[string]bool{
+	"Adlm": true,
+	"Arab": true,
+	"Hebr": true,
+	"Mand": true,
+	"Nkoo": true,
+	"Rohg": true,
+	"Samr": true,
+	"Syrc": true,
+	"Thaa": true,
+}
+
+// parseLocale parses a BCP 47 language tag, rejecting those of languages
+// without CLDR display data: the registry also has three-letter codes such
+// as api, src or lib, which are common directory names.
+func parseLocale(s string) (language.Tag, bool) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return language.Und, false
+	}
+
+	base, confidence := tag.Base()
+	if confidence != language.Exact || display.Self.Name(base) == "" {
+		return language.Und, false
+	}
+
+	return tag, true
+}
+
+// canonicalLocale returns the canonical form of a language tag, pt-BR for
+// pt_br or he for iw, or s itself when it is not a valid one.
+func canonicalLocale(s string) string {
+	if tag, ok := parseLocale(s); ok {
+		return tag.String()
+	}
+
+	return s
+}
+
+// sameLocale reports whether a and b are the same language tag, regardless
+// of case and of their form.
+func sameLocale(a string, b string) bool {
+	return strings.EqualFold(canonicalLocale(a), canonicalLocale(b))
+}
+
+// newLanguage describes a language of a repository, named in its own
+// script.
+func newLanguage(lang string) structs.Language {
+	result := structs.Language{
+		Code:      lang,
+		Tag:       canonicalLocale(lang),
+		Direction: "ltr",
+	}
+
+	tag, ok := parseLocale(lang)
+	if !ok {
+		return result
+	}
+
+	result.Name = display.Self.Name(tag)
+	if region, confidence := tag.Region(); confidence == language.Exact {
+		// most names only cover the language, keep the regions apart
+		base, _ := tag.Base()
+		script, _ := tag.Script()
+		withoutRegion, err := language.Compose(base, script)
+		if err == nil && result.Name == display.Self.Name(withoutRegion) {
+			result.Name += " (" + display.Regions(tag).Name(region) + ")"
+		}
+	}
+
+	if script, _ := tag.Script(); rtlScripts[script.String()] {
+		result.Direction = "rtl"
+	}
+
+	return result
+}
+
+// languageChain returns the languages to look for an article in, in order:
+// the requested one, its configured fallbacks or else its parent tags, such
+// as zh-Hant for zh-Hant-TW, then the default language.
+func languageChain(lang string) []string {
+	chain := []string{lang}
+
+	fallbacks, ok := configuredFallbacks(lang)
+	if !ok {
+		parent := canonicalLocale(lang)
+		for {
+			i := strings.LastIndex(parent, "-")
+			if i < 0 {
+				break
+			}
+			parent = parent[:i]
+			fallbacks = append(fallbacks, parent)
+		}
+	}
+	fallbacks = append(fallbacks, settings.Cnf.DefaultLanguage)
+
+	for _, fallback := range fallbacks {
+		if !containsLocale(chain, fallback) {
+			chain = append(chain, fallback)
+		}
+	}
+
+	return chain
+}
+
+// configuredFallbacks returns the fallbacks of a language set in the
+// languageFallbacks option, whose keys may have any case or form.
+func configuredFallbacks(lang string) ([]string, bool) {
+	for key, fallbacks := range settings.Cnf.LanguageFallbacks {
+		if sameLocale(key, lang) {
+			return append([]string(nil), fallbacks...), true
+		}
+	}
+
+	return nil, false
+}
+
+// negotiateLanguage picks the language of a repository best matching the
+// Accept-Language header of the request, or else its default language.
+func negotiateLanguage(r *http.Request, repo *structs.Repo) string {
+	for _, accepted := range parseAcceptLanguage(r.Header.Get("Accept-Language")) {
+		if accepted == "*" {
+			break
+		}
+
+		for i, lang := range languageChain(accepted) {
+			if i > 0 && sameLocale(lang, settings.Cnf.DefaultLanguage) {
+				break // only when no other accepted language matches
+			}
+
+			if supported := findLanguage(repo.Languages, lang); supported != "" {
+				return supported
+			}
+		}
+	}
+
+	return defaultLanguage(repo)
+}
+
+// defaultLanguage returns the default language when the repository supports
+// it, or else its first language.
+func defaultLanguage(repo *structs.Repo) string {
+	if supported := findLanguage(repo.Languages, settings.Cnf.DefaultLanguage); supported != "" {
+		return supported
+	}
+	if len(repo.Languages) > 0 {
+		return repo.Languages[0]
+	}
+
+	return settings.Cnf.DefaultLanguage
+}
+
+// parseAcceptLanguage returns the language ranges of an Accept-Language
+// header by decreasing preference, leaving out the refused ones.
+func parseAcceptLanguage(acceptLanguage string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, params, _ := strings.Cut(part, ";")
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(name) != "q" {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, weighted{lang, q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	langs := make([]string, len(ranges))
+	for i, r := range ranges {
+		langs[i] = r.lang
+	}
+
+	return langs
+}
+
+// findLanguage returns the language of langs matching lang, regardless of
+// case and of the form of the tags, an empty string if there is none.
+func findLanguage(langs []string, lang string) string {
+	for _, l := range langs {
+		if sameLocale(l, lang) {
+			return l
+		}
+	}
+
+	return ""
+}
+
+func containsLocale(langs []string, lang string) bool {
+	return findLanguage(langs, lang) != ""
+}
+
+// resolveLanguage returns the language of a repository matching the
+// requested one, which is returned as is when there is none.
+func resolveLanguage(repo *structs.Repo, lang string) string {
+	if supported := findLanguage(repo.Languages, lang); supported != "" {
+		return supported
+	}
+
+	return lang
+}
+
+// redirectToLanguage redirects a request to the given section of a
+// repository, in the language negotiated for it, followed by suffix. The
+// query parameters are kept.
+func redirectToLanguage(w http.ResponseWriter, r *http.Request, repo *structs.Repo, section string, suffix string) {
+	location := "/" + repo.Id + "/" + section + "/" + negotiateLanguage(r, repo)
+	if suffix != "" {
+		location += "/" + suffix
+	}
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	http.Redirect(w, r, location, http.StatusFound)
+}
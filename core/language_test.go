@@ -0,0 +1,153 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// setLanguageSettings configures the default language and the language
+// fallbacks for the duration of a test.
+func setLanguageSettings(t *testing.T, defaultLanguage string, fallbacks map[string][]string) {
+	previousDefault, previousFallbacks := settings.Cnf.DefaultLanguage, settings.Cnf.LanguageFallbacks
+	settings.Cnf.DefaultLanguage, settings.Cnf.LanguageFallbacks = defaultLanguage, fallbacks
+	t.Cleanup(func() {
+		settings.Cnf.DefaultLanguage, settings.Cnf.LanguageFallbacks = previousDefault, previousFallbacks
+	})
+}
+
+func TestIsValidLocale(t *testing.T) {
+	for _, lang := range []string{"en", "pt-BR", "pt_br", "zh-Hans", "sr-Latn", "es-419", "ar", "he", "iw"} {
+		if !isValidLocale(lang) {
+			t.Errorf("isValidLocale(%s) = false, want true", lang)
+		}
+	}
+
+	// directory names which are also registered language codes
+	for _, dir := range []string{"api", "src", "doc", "css", "lib", "bin", "dev", "old", "new", "art", "tmp", "images"} {
+		if isValidLocale(dir) {
+			t.Errorf("isValidLocale(%s) = true, want false", dir)
+		}
+	}
+}
+
+func TestLanguageChain(t *testing.T) {
+	setLanguageSettings(t, "en", map[string][]string{"es-MX": {"es-419", "es"}})
+
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{"en", []string{"en"}},
+		{"EN-us", []string{"EN-us", "en"}},
+		{"pt-BR", []string{"pt-BR", "pt", "en"}},
+		{"pt_br", []string{"pt_br", "pt", "en"}},
+		{"zh-Hans-CN", []string{"zh-Hans-CN", "zh-Hans", "zh", "en"}},
+		// configured fallbacks replace the parent languages
+		{"es-mx", []string{"es-mx", "es-419", "es", "en"}},
+	}
+
+	for _, test := range tests {
+		if got := languageChain(test.lang); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("languageChain(%s) = %q, want %q", test.lang, got, test.want)
+		}
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	setLanguageSettings(t, "en", nil)
+
+	repo := &structs.Repo{Id: "docs", Languages: []string{"en", "it", "pt-BR"}}
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"*", "en"},
+		{"it", "it"},
+		{"it-IT,it;q=0.9", "it"},
+		{"pt-br", "pt-BR"},
+		{"de;q=0, it", "it"},
+		{"fr, en;q=0.1, it;q=0.5", "it"},
+		{"IT;Q=0.8, pt-BR;q=0.9", "pt-BR"},
+		// a parent does not match its more specific languages
+		{"fr, pt;q=0.5", "en"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/docs/articles", nil)
+		r.Header.Set("Accept-Language", test.acceptLanguage)
+
+		if got := negotiateLanguage(r, repo); got != test.want {
+			t.Errorf("negotiateLanguage(%q) = %s, want %s", test.acceptLanguage, got, test.want)
+		}
+	}
+
+	// without the default language, the first one of the repository is used
+	other := &structs.Repo{Id: "other", Languages: []string{"it", "de"}}
+	r := httptest.NewRequest(http.MethodGet, "/other/articles", nil)
+	r.Header.Set("Accept-Language", "fr")
+	if got := negotiateLanguage(r, other); got != "it" {
+		t.Errorf("negotiateLanguage() without the default language = %s, want it", got)
+	}
+}
+
+func TestFindArticleTranslation(t *testing.T) {
+	setLanguageSettings(t, "en", nil)
+
+	repo := structs.Repo{
+		Id:        "docs",
+		Languages: []string{"en", "pt", "pt-BR"},
+		ArticlesGrouped: map[string][]structs.Article{
+			"en":    {{Slug: "a", Language: "en"}, {Slug: "c", Language: "en"}},
+			"pt":    {{Slug: "a", Language: "pt"}, {Slug: "c", Language: "pt", Draft: true}},
+			"pt-BR": {{Slug: "b", Language: "pt-BR"}},
+		},
+	}
+	s := newSnapshot([]structs.Repo{repo}, "test", nil)
+
+	tests := []struct {
+		lang     string
+		slug     string
+		want     string
+		fallback bool
+	}{
+		{"pt-BR", "b", "pt-BR", false},
+		{"pt-BR", "a", "pt", true},
+		{"it", "a", "en", true},
+		// hidden translations are skipped
+		{"pt", "c", "en", true},
+	}
+
+	for _, test := range tests {
+		article, ok := findArticleTranslation(s, articleFilter{}, &repo, test.lang, test.slug)
+		if !ok {
+			t.Errorf("findArticleTranslation(%s, %s) found nothing", test.lang, test.slug)
+			continue
+		}
+		if article.Language != test.want || article.Fallback != test.fallback {
+			t.Errorf("findArticleTranslation(%s, %s) = %s, fallback %t, want %s, fallback %t",
+				test.lang, test.slug, article.Language, article.Fallback, test.want, test.fallback)
+		}
+		if test.fallback && article.RequestedLanguage != test.lang {
+			t.Errorf("findArticleTranslation(%s, %s) requested language = %s", test.lang, test.slug, article.RequestedLanguage)
+		}
+	}
+
+	if _, ok := findArticleTranslation(s, articleFilter{}, &repo, "pt", "b"); ok {
+		t.Error("findArticleTranslation() found a translation of a more specific language")
+	}
+}
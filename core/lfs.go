@@ -0,0 +1,373 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/vanilla-os/Chronos/settings"
+)
+
+const (
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	lfsPointerMaxSize = 1024
+	lfsMediaType      = "application/vnd.git-lfs+json"
+	lfsBatchSize      = 100
+)
+
+var lfsClient = &http.Client{
+	Timeout: 5 * time.Minute,
+}
+
+// lfsPointer is a Git LFS pointer file found in a checkout.
+type lfsPointer struct {
+	Path string
+	Oid  string
+	Size int64
+}
+
+type lfsBatchRequest struct {
+	Operation string           `json:"operation"`
+	Transfers []string         `json:"transfers"`
+	Objects   []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchObject struct {
+	Oid     string `json:"oid"`
+	Size    int64  `json:"size"`
+	Actions *struct {
+		Download *struct {
+			Href   string            `json:"href"`
+			Header map[string]string `json:"header"`
+		} `json:"download"`
+	} `json:"actions,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsBatchObject `json:"objects"`
+}
+
+// resolveLfsPointers replaces the Git LFS pointer files found under the root
+// path of a Git checkout with the objects they point to. Objects are kept in
+// the .git/lfs/objects store, as Git LFS does, so they are downloaded only
+// once. The files which could not be resolved are logged and returned.
+func resolveLfsPointers(repo settings.ConfigRepo) ([]string, error) {
+	repoDir := gitRepoDir(repo)
+
+	pointers, err := findLfsPointers(filepath.Join(repoDir, getRootPath(repo)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for LFS pointers: %v", err)
+	}
+
+	if len(pointers) == 0 {
+		return nil, nil
+	}
+
+	log.Printf("(lfs): Resolving %d LFS objects for %s\n", len(pointers), repo.Id)
+
+	failures := make(map[string]error)
+	var toFetch []lfsPointer
+	for _, pointer := range pointers {
+		if _, err := os.Stat(lfsObjectPath(repoDir, pointer.Oid)); err != nil {
+			toFetch = append(toFetch, pointer)
+		}
+	}
+
+	if len(toFetch) > 0 {
+		endpoint, err := lfsEndpoint(repo.Url)
+		if err != nil {
+			for _, pointer := range toFetch {
+				failures[pointer.Path] = err
+			}
+		} else {
+			for start := 0; start < len(toFetch); start += lfsBatchSize {
+				end := min(start+lfsBatchSize, len(toFetch))
+				fetchLfsObjects(endpoint, repoDir, toFetch[start:end], failures)
+			}
+		}
+	}
+
+	var unresolved []string
+	for _, pointer := range pointers {
+		err, failed := failures[pointer.Path]
+		if !failed {
+			err = copyLfsObject(repoDir, pointer)
+		}
+		if err == nil {
+			continue
+		}
+
+		rel, _ := filepath.Rel(repoDir, pointer.Path)
+		log.Printf("(lfs): Unable to resolve LFS object for %s in %s: %v\n", rel, repo.Id, err)
+		unresolved = append(unresolved, filepath.ToSlash(rel))
+	}
+
+	return unresolved, nil
+}
+
+// findLfsPointers returns the LFS pointer files under dir. Submodules are
+// skipped, their objects not being stored on the LFS server of the
+// repository.
+func findLfsPointers(dir string) ([]lfsPointer, error) {
+	var pointers []lfsPointer
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == git.GitDirName {
+				return filepath.SkipDir
+			}
+
+			if path != dir {
+				if _, err := os.Lstat(filepath.Join(path, git.GitDirName)); err == nil {
+					return filepath.SkipDir
+				}
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > lfsPointerMaxSize {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		pointer, ok := parseLfsPointer(content)
+		if ok {
+			pointer.Path = path
+			pointers = append(pointers, pointer)
+		}
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return pointers, err
+}
+
+// parseLfsPointer parses the content of an LFS pointer file.
+func parseLfsPointer(content []byte) (lfsPointer, bool) {
+	if !bytes.HasPrefix(content, []byte(lfsPointerVersion+"\n")) {
+		return lfsPointer{}, false
+	}
+
+	var pointer lfsPointer
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			pointer.Oid = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			pointer.Size, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+
+	// the oid names the object file, so it must be a lowercase hex digest
+	if !isLfsOid(pointer.Oid) {
+		return lfsPointer{}, false
+	}
+
+	return pointer, true
+}
+
+func isLfsOid(oid string) bool {
+	if len(oid) != sha256.Size*2 || strings.ToLower(oid) != oid {
+		return false
+	}
+
+	_, err := hex.DecodeString(oid)
+	return err == nil
+}
+
+// lfsEndpoint returns the LFS server URL of an HTTP(S) Git remote, following
+// the Git LFS default of <remote>.git/info/lfs.
+func lfsEndpoint(url string) (string, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return "", fmt.Errorf("LFS is only supported for HTTP(S) remotes")
+	}
+
+	url = strings.TrimSuffix(url, "/")
+	if !strings.HasSuffix(url, ".git") {
+		url += ".git"
+	}
+
+	return url + "/info/lfs", nil
+}
+
+func lfsObjectPath(repoDir string, oid string) string {
+	return filepath.Join(repoDir, git.GitDirName, "lfs", "objects", oid[0:2], oid[2:4], oid)
+}
+
+// fetchLfsObjects downloads the given objects to the LFS store, recording
+// the failures by pointer path.
+func fetchLfsObjects(endpoint string, repoDir string, pointers []lfsPointer, failures map[string]error) {
+	request := lfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+	}
+	for _, pointer := range pointers {
+		request.Objects = append(request.Objects, lfsBatchObject{Oid: pointer.Oid, Size: pointer.Size})
+	}
+
+	response, err := lfsBatch(endpoint, request)
+	if err != nil {
+		for _, pointer := range pointers {
+			failures[pointer.Path] = err
+		}
+		return
+	}
+
+	objects := make(map[string]lfsBatchObject, len(response.Objects))
+	for _, object := range response.Objects {
+		objects[object.Oid] = object
+	}
+
+	for _, pointer := range pointers {
+		object, ok := objects[pointer.Oid]
+		switch {
+		case !ok:
+			err = fmt.Errorf("object %s missing from LFS server response", pointer.Oid)
+		case object.Error != nil:
+			err = fmt.Errorf("LFS server error %d: %s", object.Error.Code, object.Error.Message)
+		case object.Actions == nil || object.Actions.Download == nil:
+			err = fmt.Errorf("no download action for object %s", pointer.Oid)
+		default:
+			err = downloadLfsObject(object.Actions.Download.Href, object.Actions.Download.Header, repoDir, pointer)
+		}
+
+		if err != nil {
+			failures[pointer.Path] = err
+		}
+	}
+}
+
+func lfsBatch(endpoint string, request lfsBatchRequest) (*lfsBatchResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+
+	resp, err := lfsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("LFS batch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LFS batch request failed: %s", resp.Status)
+	}
+
+	var response lfsBatchResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LFS batch response: %v", err)
+	}
+
+	return &response, nil
+}
+
+// downloadLfsObject downloads an object to the LFS store, verifying its
+// checksum before making it available.
+func downloadLfsObject(href string, header map[string]string, repoDir string, pointer lfsPointer) error {
+	req, err := http.NewRequest(http.MethodGet, href, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := lfsClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("LFS download failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("LFS download failed: %s", resp.Status)
+	}
+
+	objectPath := lfsObjectPath(repoDir, pointer.Oid)
+	err = os.MkdirAll(filepath.Dir(objectPath), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), pointer.Oid+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("LFS download failed: %v", err)
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != pointer.Oid {
+		return fmt.Errorf("checksum mismatch for object %s", pointer.Oid)
+	}
+
+	return os.Rename(tmp.Name(), objectPath)
+}
+
+// copyLfsObject replaces a pointer file with its object from the LFS store.
+func copyLfsObject(repoDir string, pointer lfsPointer) error {
+	content, err := os.ReadFile(lfsObjectPath(repoDir, pointer.Oid))
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pointer.Path, content, 0644)
+}
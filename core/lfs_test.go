@@ -0,0 +1,76 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lfsPointerContent(oid string) string {
+	return lfsPointerVersion + "\noid sha256:" + oid + "\nsize 12\n"
+}
+
+func TestParseLfsPointer(t *testing.T) {
+	valid := strings.Repeat("0123456789abcdef", 4)
+
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"valid", lfsPointerContent(valid), true},
+		{"traversal", lfsPointerContent(strings.Repeat("../", 16) + "etc/ssh/host_key"), false},
+		{"uppercase", lfsPointerContent(strings.ToUpper(valid)), false},
+		{"short", lfsPointerContent(valid[:40]), false},
+		{"not a pointer", "# Title\n\noid sha256:" + valid, false},
+	}
+
+	for _, test := range tests {
+		pointer, ok := parseLfsPointer([]byte(test.content))
+		if ok != test.want {
+			t.Errorf("%s: parseLfsPointer() = %v, want %v", test.name, ok, test.want)
+		}
+		if ok && (pointer.Oid != valid || pointer.Size != 12) {
+			t.Errorf("%s: parseLfsPointer() = %+v", test.name, pointer)
+		}
+	}
+}
+
+func TestFindLfsPointersSkipsSubmodules(t *testing.T) {
+	dir := t.TempDir()
+	pointer := lfsPointerContent(strings.Repeat("ab", 32))
+
+	files := map[string]string{
+		"en/image.md":             pointer,
+		"en/plain.md":             "# Plain\n",
+		"vendor/docs/.git":        "gitdir: ../../.git/modules/docs\n",
+		"vendor/docs/en/image.md": pointer,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pointers, err := findLfsPointers(dir)
+	if err != nil {
+		t.Fatalf("findLfsPointers() failed: %v", err)
+	}
+	if len(pointers) != 1 || pointers[0].Path != filepath.Join(dir, "en/image.md") {
+		t.Errorf("findLfsPointers() = %+v, want only en/image.md", pointers)
+	}
+}
@@ -11,12 +11,14 @@ package core
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -44,11 +46,35 @@ func LoadChronos() (err error) {
 		return err
 	}
 
-	err = prepareRepos(true)
+	err = InitCoordinator()
 	if err != nil {
 		return err
 	}
 
+	if settings.Cnf.RedisCoordination {
+		err = watchContentVersions(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	// the disk backend persists the repositories across restarts, so they
+	// are served right away while the checkouts are synchronized
+	if cacheBackend == "disk" && loadSharedRepos(context.Background()) == nil {
+		log.Println("(loader): Serving the repositories persisted on disk while synchronizing")
+		go func() {
+			err := prepareOrLoadRepos()
+			if err != nil {
+				log.Printf("(loader): Failed to prepare repos, still serving the persisted ones: %v\n", err)
+			}
+		}()
+	} else {
+		err = prepareOrLoadRepos()
+		if err != nil {
+			return err
+		}
+	}
+
 	if settings.Cnf.BackgroundCacheUpdate {
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -59,33 +85,41 @@ func LoadChronos() (err error) {
 	return nil
 }
 
+// prepareOrLoadRepos prepares the repositories, unless another instance is
+// synchronizing already: in that case its content is served once available
+// in the shared cache.
+func prepareOrLoadRepos() error {
+	for {
+		prepared, err := prepareReposExclusively(true)
+		if err != nil {
+			return err
+		}
+		if prepared || loadSharedRepos(context.Background()) == nil {
+			return nil
+		}
+
+		log.Printf("(loader): Another instance is synchronizing, retrying in %s\n", syncLockRetryDelay)
+		time.Sleep(syncLockRetryDelay)
+	}
+}
+
 // backgroundCacheUpdate updates the cache in the background.
 func backgroundCacheUpdate(interval time.Duration, wg *sync.WaitGroup) {
 	for {
 		log.Println("(loader): Starting background cache update...")
 
-		for _, repo := range settings.Cnf.GitRepos {
-			changed, err := detectGitChanges(repo.Url)
-			if err != nil {
-				log.Printf("(loader): Failed to detect Git changes: %v\n", err)
-			}
-
-			if changed {
-				err := synGitRepo(repo.Url, true)
-				if err != nil {
-					log.Printf("(loader): Failed to synchronize Git repository: %v\n", err)
-				}
-			}
-		}
-
-		err := prepareRepos(false)
-		if err != nil {
-			log.Printf("(loader): Failed to prepare repos: %v\n", err)
-			continue
+		// a failed update keeps the previous repositories served, the next
+		// attempt is made after the usual interval
+		updated, err := updateReposExclusively()
+		switch {
+		case err != nil:
+			log.Printf("(loader): Failed to prepare repos, serving the previous ones: %v\n", err)
+		case updated:
+			log.Println("(loader): Finished background cache update")
+		default:
+			log.Println("(loader): Another instance is synchronizing, skipped background cache update")
 		}
 
-		log.Println("(loader): Finished background cache update")
-
 		if wg != nil {
 			wg.Done()
 			wg = nil
@@ -98,6 +132,38 @@ func backgroundCacheUpdate(interval time.Duration, wg *sync.WaitGroup) {
 	}
 }
 
+// updateReposExclusively synchronizes the changed Git repositories and
+// prepares the repositories again, only if no other instance is doing it.
+func updateReposExclusively() (bool, error) {
+	release, ok, err := coordinator.TryLock(context.Background())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire the sync lock: %v", err)
+	}
+	if !ok {
+		return false, nil
+	}
+	defer release()
+
+	for _, repo := range settings.Cnf.GitRepos {
+		changed, err := detectGitChanges(repo)
+		if err != nil {
+			log.Printf("(loader): Failed to detect Git changes: %v\n", err)
+		}
+
+		if changed {
+			err := synGitRepo(repo)
+			if err != nil {
+				log.Printf("(loader): Failed to synchronize Git repository: %v\n", err)
+			}
+		}
+	}
+
+	err = prepareRepos(false)
+	recordReload(err)
+
+	return true, err
+}
+
 // prepareRepos prepares both local and Git repositories.
 func prepareRepos(needSyncGit bool) error {
 	var repos []structs.Repo
@@ -105,15 +171,24 @@ func prepareRepos(needSyncGit bool) error {
 
 	log.Println("(loader): Preparing Git repositories cache")
 
-	for _, repo := range settings.Cnf.GitRepos {
-		rootPath := "articles"
-		if repo.RootPath != "" {
-			rootPath = repo.RootPath
+	if needSyncGit {
+		err = cleanupStaleCheckouts()
+		if err != nil {
+			return fmt.Errorf("failed to clean up stale checkouts: %v", err)
 		}
 
+		err = cleanupPreviewCheckouts()
+		if err != nil {
+			return fmt.Errorf("failed to clean up preview checkouts: %v", err)
+		}
+	}
+
+	for _, repo := range settings.Cnf.GitRepos {
+		rootPath := getRootPath(repo)
+
 		if needSyncGit {
 			log.Printf("(loader): Synchronizing Git repository: %s\n", repo.Url)
-			err := synGitRepo(repo.Url, false)
+			err := synGitRepo(repo)
 			if err != nil {
 				return fmt.Errorf("failed to synchronize Git repository: %v", err)
 			}
@@ -121,11 +196,19 @@ func prepareRepos(needSyncGit bool) error {
 
 		_repo := structs.Repo{
 			Id:           repo.Id,
-			Path:         reposDir + strings.ReplaceAll(repo.Url, "/", "_"),
+			Path:         gitRepoDir(repo),
 			RootPath:     rootPath,
 			FallbackLang: repo.FallbackLang,
 		}
 
+		if repo.Lfs {
+			log.Printf("(loader): Resolving LFS objects for Git repository: %s\n", repo.Url)
+			_repo.UnresolvedLfsFiles, err = resolveLfsPointers(repo)
+			if err != nil {
+				return err
+			}
+		}
+
 		log.Printf("(loader): Loading languages for Git repository: %s\n", repo.Url)
 		_repo.Languages, err = getRepoLanguages(&_repo)
 		if err != nil {
@@ -138,17 +221,36 @@ func prepareRepos(needSyncGit bool) error {
 			return err
 		}
 
+		log.Printf("(loader): Loading tags and authors for Git repository: %s\n", repo.Url)
+		_repo.Tags, err = loadTags(&_repo)
+		if err != nil {
+			return err
+		}
+
+		_repo.Authors, err = loadAuthors(&_repo)
+		if err != nil {
+			return err
+		}
+
 		log.Printf("(loader): Loading articles for Git repository: %s\n", repo.Url)
 		_repo.Articles, err = getRepoArticles(_repo)
 		if err != nil {
 			return err
 		}
 
+		log.Printf("(loader): Loading history for Git repository: %s\n", repo.Url)
+		_repo.History, err = loadRepoHistory(_repo)
+		if err != nil {
+			return err
+		}
+		applyArticleHistory(&_repo)
+
 		log.Printf("(loader): Grouping articles for Git repository: %s\n", repo.Url)
 		_repo.ArticlesGrouped, err = groupArticles(_repo)
 		if err != nil {
 			return err
 		}
+		checkStories(&_repo)
 
 		repos = append(repos, _repo)
 	}
@@ -180,29 +282,107 @@ func prepareRepos(needSyncGit bool) error {
 			return err
 		}
 
+		log.Printf("(loader): Loading tags and authors for local repository: %s\n", repo.Url)
+		_repo.Tags, err = loadTags(&_repo)
+		if err != nil {
+			return err
+		}
+
+		_repo.Authors, err = loadAuthors(&_repo)
+		if err != nil {
+			return err
+		}
+
 		log.Printf("(loader): Loading articles for local repository: %s\n", repo.Url)
 		_repo.Articles, err = getRepoArticles(_repo)
 		if err != nil {
 			return err
 		}
 
+		log.Printf("(loader): Loading history for local repository: %s\n", repo.Url)
+		_repo.History, err = loadRepoHistory(_repo)
+		if err != nil {
+			return err
+		}
+		applyArticleHistory(&_repo)
+
 		log.Printf("(loader): Grouping articles for local repository: %s\n", repo.Url)
 		_repo.ArticlesGrouped, err = groupArticles(_repo)
 		if err != nil {
 			return err
 		}
+		checkStories(&_repo)
 
 		repos = append(repos, _repo)
 	}
 
-	reposBytes, err := json.Marshal(repos)
+	version, err := contentVersion(repos)
+	if err != nil {
+		return fmt.Errorf("failed to compute content version: %v", err)
+	}
+
+	publishSnapshot(repos, version)
+
+	// the repos are kept in the cache backend for the instances sharing it,
+	// lookups are served from the in-memory snapshot
+	ctx := context.Background()
+	err = storeReposInCache(ctx, repos, version)
+	if err != nil {
+		log.Printf("(loader): Failed to cache repos: %v\n", err)
+	} else {
+		announceContentVersion(ctx, version)
+	}
+
+	log.Printf("(loader): Finished preparing repositories cache: %d repos, version %s\n", len(repos), version)
+
+	return nil
+}
+
+// prepareReposExclusively prepares the repositories only if no other
+// instance is doing it, reporting whether it did.
+func prepareReposExclusively(needSyncGit bool) (bool, error) {
+	release, ok, err := coordinator.TryLock(context.Background())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire the sync lock: %v", err)
+	}
+	if !ok {
+		return false, nil
+	}
+	defer release()
+
+	err = prepareRepos(needSyncGit)
+	recordReload(err)
+
+	return true, err
+}
+
+// announceContentVersion notifies the other instances about the content
+// version storeReposInCache made current.
+func announceContentVersion(ctx context.Context, version string) {
+	err := coordinator.Publish(ctx, version)
+	if err != nil {
+		log.Printf("(loader): Failed to announce content version: %v\n", err)
+	}
+}
+
+// loadSharedRepos serves the repositories cached by another instance. The
+// keys of a content version are never rewritten, so the repositories read
+// are either all of that version or fail to load when it is invalidated in
+// the meantime.
+func loadSharedRepos(ctx context.Context) error {
+	var version string
+	err := getCacheEntry(ctx, contentVersionKey, &version)
 	if err != nil {
-		log.Printf("(loader): Failed to marshal repos: %v\n", err)
+		return err
 	}
 
-	cacheManager.Set(context.Background(), "Repos", reposBytes)
+	repos, err := loadReposFromCache(ctx, version)
+	if err != nil {
+		return err
+	}
 
-	log.Printf("(loader): Finished preparing repositories cache: %d repos\n", len(repos))
+	publishSnapshot(repos, version)
+	log.Printf("(loader): Loaded shared repositories cache: %d repos, version %s\n", len(repos), version)
 
 	return nil
 }
@@ -265,6 +445,13 @@ func groupArticles(repo structs.Repo) (map[string][]structs.Article, error) {
 		tmpArticleCacheGrouped[article.Language] = append(tmpArticleCacheGrouped[article.Language], article)
 	}
 
+	// keep a stable order across reloads, the content version depends on it
+	for _, articles := range tmpArticleCacheGrouped {
+		sort.Slice(articles, func(i, j int) bool {
+			return articles[i].Path < articles[j].Path
+		})
+	}
+
 	return tmpArticleCacheGrouped, nil
 }
 
@@ -277,7 +464,12 @@ func loadLanguagesFromRepo(repo *structs.Repo) ([]string, error) {
 	}
 
 	for _, entry := range dirEntries {
-		if !entry.IsDir() || !isValidLocale(entry.Name()) {
+		if !entry.IsDir() {
+			continue
+		}
+
+		if !isValidLocale(entry.Name()) {
+			log.Printf("(loader): Ignoring directory %s of repo %s, not a language tag\n", entry.Name(), repo.Path)
 			continue
 		}
 
@@ -353,6 +545,12 @@ func loadArticle(repo structs.Repo, path string) (structs.Article, error) {
 		return structs.Article{}, err
 	}
 
+	for _, date := range []string{header.PublicationDate, header.ExpiryDate} {
+		if _, ok := parseArticleDate(date); date != "" && !ok {
+			log.Printf("(loader): Ignoring invalid date %q in article: %s\n", date, path)
+		}
+	}
+
 	slug := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 
 	lang := repo.FallbackLang
@@ -367,21 +565,29 @@ func loadArticle(repo structs.Repo, path string) (structs.Article, error) {
 	if err != nil {
 		return structs.Article{}, fmt.Errorf("failed to load story: %v", err)
 	}
+	*story = localizeStory(*story, lang)
 
 	parsedBody := blackfriday.Run([]byte(body))
+	contentHash := sha256.Sum256([]byte(body))
 
 	article := structs.Article{
 		StoryId:         header.StoryId,
 		Story:           story,
 		Previous:        header.Previous,
 		Next:            header.Next,
-		Listed:          header.Listed,
+		Listed:          header.Listed == nil || *header.Listed,
+		Draft:           header.Draft,
 		Title:           header.Title,
 		Description:     header.Description,
 		PublicationDate: header.PublicationDate,
+		ExpiryDate:      header.ExpiryDate,
 		Authors:         header.Authors,
 		Tags:            header.Tags,
+		Weight:          header.Weight,
+		TranslationKey:  header.TranslationKey,
+		SourceHash:      strings.ToLower(header.SourceHash),
 		Body:            string(parsedBody),
+		ContentHash:     hex.EncodeToString(contentHash[:]),
 		Path:            path,
 		Url:             strings.TrimSuffix(path, filepath.Ext(path)),
 		Slug:            slug,
@@ -391,17 +597,12 @@ func loadArticle(repo structs.Repo, path string) (structs.Article, error) {
 	return article, nil
 }
 
-// loadStories loads all the stories from the stories.yml file in the repository.
+// loadStories loads all the stories from the stories.yml file in the
+// repository, along with their translations from the stories.yml files of
+// the languages.
 func loadStories(repo *structs.Repo) (map[string]structs.Story, error) {
-	storiesPath := filepath.Join(repo.Path, repo.RootPath, "stories.yml")
-	storiesFile, err := os.ReadFile(storiesPath)
-	if err != nil {
-		fmt.Printf("(loader): No stories file found for repo: %s\n", repo.Path)
-		return nil, nil // safe to ignore, stories file is optional
-	}
-
 	var stories []structs.Story
-	err = yaml.Unmarshal(storiesFile, &stories)
+	err := loadRepoYaml(repo, "stories.yml", &stories)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal stories file: %v", err)
 	}
@@ -409,12 +610,83 @@ func loadStories(repo *structs.Repo) (map[string]structs.Story, error) {
 	storiesMap := make(map[string]structs.Story)
 	for _, story := range stories {
 		storiesMap[story.Id] = story
-		fmt.Printf("(loader): Loaded story: %s\n", story.Name)
+		fmt.Printf("(loader): Loaded story: %s\n", story.Id)
+	}
+
+	for _, lang := range repo.Languages {
+		var translated []structs.Story
+		err := loadRepoYaml(repo, filepath.Join(lang, "stories.yml"), &translated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s stories file: %v", lang, err)
+		}
+
+		for _, story := range translated {
+			storiesMap[story.Id] = translateStory(storiesMap[story.Id], lang, story)
+		}
+	}
+
+	if len(storiesMap) == 0 {
+		fmt.Printf("(loader): No stories file found for repo: %s\n", repo.Path)
+		return nil, nil // safe to ignore, stories file is optional
 	}
 
 	return storiesMap, nil
 }
 
+// loadTags loads the tag descriptions from the optional tags.yml file in
+// the repository.
+func loadTags(repo *structs.Repo) (map[string]structs.Tag, error) {
+	var tags []structs.Tag
+	err := loadRepoYaml(repo, "tags.yml", &tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tags file: %v", err)
+	}
+	if tags == nil {
+		return nil, nil
+	}
+
+	tagsMap := make(map[string]structs.Tag, len(tags))
+	for _, tag := range tags {
+		tagsMap[tag.Id] = tag
+	}
+
+	return tagsMap, nil
+}
+
+// loadAuthors loads the author profiles from the optional authors.yml file
+// in the repository.
+func loadAuthors(repo *structs.Repo) (map[string]structs.Author, error) {
+	var authors []structs.Author
+	err := loadRepoYaml(repo, "authors.yml", &authors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load authors file: %v", err)
+	}
+	if authors == nil {
+		return nil, nil
+	}
+
+	authorsMap := make(map[string]structs.Author, len(authors))
+	for _, author := range authors {
+		authorsMap[author.Id] = author
+	}
+
+	return authorsMap, nil
+}
+
+// loadRepoYaml unmarshals a YAML file from the root path of the repository
+// into out, which is left untouched when the file does not exist.
+func loadRepoYaml(repo *structs.Repo, name string, out any) error {
+	data, err := os.ReadFile(filepath.Join(repo.Path, repo.RootPath, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(data, out)
+}
+
 // loadStory loads a story from the repository's stories map using its ID.
 func loadStory(repo structs.Repo, storyId string) (*structs.Story, error) {
 	if storyId == "" {
@@ -0,0 +1,297 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanilla-os/Chronos/settings"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+const (
+	previewTokenTTL    = time.Hour
+	previewTokenMaxTTL = 30 * 24 * time.Hour
+
+	// previewRefreshInterval is how often the branch of a preview is fetched
+	// again while it is being requested
+	previewRefreshInterval = time.Minute
+
+	// previewDirName is the directory, under reposDir, of the branches
+	// checked out for previews
+	previewDirName = ".preview"
+
+	// previewIdleTTL is how long the branch of a preview is kept once it is
+	// not requested anymore
+	previewIdleTTL = 24 * time.Hour
+)
+
+var (
+	errPreviewDisabled     = errors.New("previews are disabled, previewKey is not configured")
+	errInvalidPreviewToken = errors.New("invalid preview token")
+	errExpiredPreviewToken = errors.New("expired preview token")
+)
+
+// previewClaims is the content of a preview token. A token limited to a
+// repository may also preview another branch of it.
+type previewClaims struct {
+	Repo    string `json:"repo,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Expires int64  `json:"exp"`
+}
+
+// signPreviewToken returns a token carrying the given claims, signed with
+// the configured preview key.
+func signPreviewToken(claims previewClaims) (string, error) {
+	if settings.Cnf.PreviewKey == "" {
+		return "", errPreviewDisabled
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + previewSignature(encoded), nil
+}
+
+// parsePreviewToken verifies the signature and the expiration of a preview
+// token, returning its claims.
+func parsePreviewToken(token string) (*previewClaims, error) {
+	if settings.Cnf.PreviewKey == "" {
+		return nil, errPreviewDisabled
+	}
+
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(previewSignature(payload))) {
+		return nil, errInvalidPreviewToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errInvalidPreviewToken
+	}
+
+	var claims previewClaims
+	err = json.Unmarshal(data, &claims)
+	if err != nil {
+		return nil, errInvalidPreviewToken
+	}
+
+	if time.Now().Unix() >= claims.Expires {
+		return nil, errExpiredPreviewToken
+	}
+
+	return &claims, nil
+}
+
+func previewSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(settings.Cnf.PreviewKey))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getPreviewClaims returns the claims of the preview token of a request,
+// passed in the X-Preview-Token header or the preview query parameter. It
+// is nil when there is no valid token, the request being served as any
+// other one.
+func getPreviewClaims(r *http.Request) *previewClaims {
+	token := r.Header.Get("X-Preview-Token")
+	if token == "" {
+		token = r.URL.Query().Get("preview")
+	}
+	if token == "" {
+		return nil
+	}
+
+	claims, err := parsePreviewToken(token)
+	if err != nil {
+		return nil
+	}
+
+	return claims
+}
+
+// covers reports whether the claims allow previewing the given repository,
+// an empty ID standing for all of them.
+func (c *previewClaims) covers(repoId string) bool {
+	return c != nil && (c.Repo == "" || c.Repo == repoId)
+}
+
+// branchPreview is a snapshot where a repository is loaded from another
+// branch, the other ones being those of the snapshot it is based on. Its
+// lock is held while the branch is checked out, so that only the requests
+// previewing that branch wait for it.
+type branchPreview struct {
+	mu          sync.Mutex
+	s           *snapshot
+	baseVersion string
+	loadedAt    time.Time
+}
+
+var (
+	branchPreviews   = make(map[string]*branchPreview) // repo ID + branch
+	branchPreviewsMu sync.Mutex
+)
+
+// requestSnapshot returns the snapshot serving a request: the current one,
+// or the one of the branch its preview token is for.
+func requestSnapshot(r *http.Request) *snapshot {
+	claims := getPreviewClaims(r)
+	if claims == nil || claims.Branch == "" {
+		return getSnapshot()
+	}
+
+	s, err := getBranchPreview(claims.Repo, claims.Branch)
+	if err != nil {
+		log.Printf("(preview): Unable to preview branch %s of %s: %v\n", claims.Branch, claims.Repo, err)
+		return getSnapshot()
+	}
+
+	return s
+}
+
+// getBranchPreview returns the snapshot previewing a branch of a Git
+// repository, checking it out again when it is older than
+// previewRefreshInterval or the current snapshot changed.
+func getBranchPreview(repoId string, branch string) (*snapshot, error) {
+	base := getSnapshot()
+	if base == nil {
+		return nil, errors.New("repos not loaded")
+	}
+
+	key := repoId + "\x00" + branch
+	branchPreviewsMu.Lock()
+	preview, ok := branchPreviews[key]
+	if !ok {
+		preview = &branchPreview{}
+		branchPreviews[key] = preview
+	}
+	branchPreviewsMu.Unlock()
+
+	preview.mu.Lock()
+	defer preview.mu.Unlock()
+
+	if preview.s != nil && preview.baseVersion == base.version && time.Since(preview.loadedAt) < previewRefreshInterval {
+		return preview.s, nil
+	}
+
+	previewRepo, err := loadBranchPreviewRepo(repoId, branch)
+	if err != nil {
+		// branches which were never checked out are not kept
+		branchPreviewsMu.Lock()
+		if preview.s == nil && branchPreviews[key] == preview {
+			delete(branchPreviews, key)
+		}
+		branchPreviewsMu.Unlock()
+		return nil, err
+	}
+
+	repos := make([]structs.Repo, len(base.repos))
+	for i, repo := range base.repos {
+		repos[i] = *repo
+		if repo.Id == repoId {
+			repos[i] = previewRepo
+		}
+	}
+
+	preview.s = newSnapshot(repos, base.version+"+"+branch, base)
+	preview.baseVersion = base.version
+	preview.loadedAt = time.Now()
+
+	return preview.s, nil
+}
+
+// pruneBranchPreviews forgets the branch previews which were not requested
+// for previewIdleTTL, skipping those being checked out.
+func pruneBranchPreviews() {
+	branchPreviewsMu.Lock()
+	defer branchPreviewsMu.Unlock()
+
+	for key, preview := range branchPreviews {
+		if !preview.mu.TryLock() {
+			continue
+		}
+		if time.Since(preview.loadedAt) >= previewIdleTTL {
+			delete(branchPreviews, key)
+		}
+		preview.mu.Unlock()
+	}
+}
+
+// loadBranchPreviewRepo loads a repository from a branch checked out for
+// previews. LFS pointers are not resolved and there is no history.
+func loadBranchPreviewRepo(repoId string, branch string) (structs.Repo, error) {
+	var config *settings.ConfigRepo
+	for i := range settings.Cnf.GitRepos {
+		if settings.Cnf.GitRepos[i].Id == repoId {
+			config = &settings.Cnf.GitRepos[i]
+		}
+	}
+	if config == nil {
+		return structs.Repo{}, fmt.Errorf("%s is not a Git repository", repoId)
+	}
+
+	log.Printf("(preview): Checking out branch %s of %s\n", branch, repoId)
+	dir, err := checkoutPreviewBranch(*config, branch)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	repo := structs.Repo{
+		Id:           config.Id,
+		Path:         dir,
+		RootPath:     getRootPath(*config),
+		FallbackLang: config.FallbackLang,
+	}
+
+	repo.Languages, err = getRepoLanguages(&repo)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	repo.Stories, err = loadStories(&repo)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	repo.Tags, err = loadTags(&repo)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	repo.Authors, err = loadAuthors(&repo)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	repo.Articles, err = getRepoArticles(repo)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	repo.ArticlesGrouped, err = groupArticles(repo)
+	if err != nil {
+		return structs.Repo{}, err
+	}
+
+	return repo, nil
+}
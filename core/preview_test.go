@@ -0,0 +1,151 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vanilla-os/Chronos/settings"
+)
+
+// setPreviewKey configures the preview key for the duration of a test.
+func setPreviewKey(t *testing.T, key string) {
+	previousKey := settings.Cnf.PreviewKey
+	settings.Cnf.PreviewKey = key
+	t.Cleanup(func() { settings.Cnf.PreviewKey = previousKey })
+}
+
+func TestPreviewToken(t *testing.T) {
+	setPreviewKey(t, "secret")
+
+	valid, err := signPreviewToken(previewClaims{Repo: "docs", Branch: "next", Expires: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("signPreviewToken() failed: %v", err)
+	}
+
+	claims, err := parsePreviewToken(valid)
+	if err != nil {
+		t.Fatalf("parsePreviewToken() failed: %v", err)
+	}
+	if claims.Repo != "docs" || claims.Branch != "next" {
+		t.Errorf("parsePreviewToken() = %+v, want the signed claims", claims)
+	}
+
+	expired, err := signPreviewToken(previewClaims{Expires: time.Now().Add(-time.Second).Unix()})
+	if err != nil {
+		t.Fatalf("signPreviewToken() failed: %v", err)
+	}
+
+	payload, signature, _ := strings.Cut(valid, ".")
+	forged, _ := signPreviewToken(previewClaims{Expires: time.Now().Add(time.Hour).Unix()})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	for name, test := range map[string]struct {
+		token string
+		want  error
+	}{
+		"expired":         {expired, errExpiredPreviewToken},
+		"unsigned":        {payload, errInvalidPreviewToken},
+		"swapped payload": {forgedPayload + "." + signature, errInvalidPreviewToken},
+		"truncated":       {valid[:len(valid)-1], errInvalidPreviewToken},
+		"invalid base64":  {"!." + previewSignature("!"), errInvalidPreviewToken},
+		"invalid claims":  {"bm90IGpzb24." + previewSignature("bm90IGpzb24"), errInvalidPreviewToken},
+	} {
+		_, err := parsePreviewToken(test.token)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: parsePreviewToken() = %v, want %v", name, err, test.want)
+		}
+	}
+
+	// a token signed with another key is rejected
+	setPreviewKey(t, "other")
+	if _, err := parsePreviewToken(valid); !errors.Is(err, errInvalidPreviewToken) {
+		t.Errorf("parsePreviewToken() with another key = %v, want %v", err, errInvalidPreviewToken)
+	}
+
+	setPreviewKey(t, "")
+	if _, err := signPreviewToken(previewClaims{}); !errors.Is(err, errPreviewDisabled) {
+		t.Errorf("signPreviewToken() without a key = %v, want %v", err, errPreviewDisabled)
+	}
+	if _, err := parsePreviewToken(valid); !errors.Is(err, errPreviewDisabled) {
+		t.Errorf("parsePreviewToken() without a key = %v, want %v", err, errPreviewDisabled)
+	}
+}
+
+func TestGetPreviewClaims(t *testing.T) {
+	setPreviewKey(t, "secret")
+
+	token, err := signPreviewToken(previewClaims{Repo: "docs", Expires: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("signPreviewToken() failed: %v", err)
+	}
+
+	header := httptest.NewRequest(http.MethodGet, "/docs/articles/en", nil)
+	header.Header.Set("X-Preview-Token", token)
+	query := httptest.NewRequest(http.MethodGet, "/docs/articles/en?preview="+token, nil)
+	invalid := httptest.NewRequest(http.MethodGet, "/docs/articles/en?preview=invalid", nil)
+
+	for name, test := range map[string]struct {
+		claims *previewClaims
+		repo   string
+		want   bool
+	}{
+		"header":        {getPreviewClaims(header), "docs", true},
+		"query":         {getPreviewClaims(query), "docs", true},
+		"other repo":    {getPreviewClaims(header), "other", false},
+		"invalid token": {getPreviewClaims(invalid), "docs", false},
+		"all repos":     {&previewClaims{}, "other", true},
+	} {
+		if got := test.claims.covers(test.repo); got != test.want {
+			t.Errorf("%s: covers(%s) = %t, want %t", name, test.repo, got, test.want)
+		}
+	}
+}
+
+func TestCleanupPreviewCheckouts(t *testing.T) {
+	previousDir := reposDir
+	reposDir = t.TempDir()
+	t.Cleanup(func() { reposDir = previousDir })
+
+	previewDir := filepath.Join(reposDir, previewDirName)
+	idle := time.Now().Add(-previewIdleTTL - time.Minute)
+	for name, modTime := range map[string]time.Time{"idle": idle, "recent": time.Now()} {
+		for _, dir := range []string{name + ".git", name} {
+			err := os.MkdirAll(filepath.Join(previewDir, dir), 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := os.Chtimes(filepath.Join(previewDir, name+".git"), modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := cleanupPreviewCheckouts()
+	if err != nil {
+		t.Fatalf("cleanupPreviewCheckouts() failed: %v", err)
+	}
+
+	for dir, want := range map[string]bool{"idle.git": false, "idle": false, "recent.git": true, "recent": true} {
+		_, err := os.Stat(filepath.Join(previewDir, dir))
+		if (err == nil) != want {
+			t.Errorf("%s exists: %v, want %v", dir, err == nil, want)
+		}
+	}
+}
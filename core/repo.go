@@ -10,31 +10,11 @@ package core
 */
 
 import (
-	"context"
-	"encoding/json"
-	"errors"
-
 	"github.com/vanilla-os/Chronos/structs"
 )
 
+// getRepo returns the repository with the given ID from the current
+// snapshot. The returned repository is shared and must not be modified.
 func getRepo(repoId string) (*structs.Repo, error) {
-	var repos []structs.Repo
-
-	cRepos, err := cacheManager.Get(context.Background(), "Repos")
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(cRepos, &repos)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, repo := range repos {
-		if repo.Id == repoId {
-			return &repo, nil
-		}
-	}
-
-	return nil, errors.New("repo not found")
+	return getSnapshot().getRepo(repoId)
 }
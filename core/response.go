@@ -0,0 +1,175 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vanilla-os/Chronos/settings"
+)
+
+// renderedResponse is a response body along with its validators.
+type renderedResponse struct {
+	body         []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+
+	memoized bool
+	variants sync.Map // content coding -> encoded body, see encoded
+}
+
+// newRenderedResponse wraps a body, deriving its ETag from its content.
+func newRenderedResponse(body []byte, contentType string, lastModified time.Time) *renderedResponse {
+	sum := sha256.Sum256(body)
+
+	return &renderedResponse{
+		body:         body,
+		contentType:  contentType,
+		etag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		lastModified: lastModified.UTC().Truncate(time.Second),
+	}
+}
+
+// responseHits and responseMisses count the memoized responses found and
+// rendered by renderJSON.
+var responseHits, responseMisses atomic.Uint64
+
+// renderJSON marshals the value returned by build. Responses with a key are
+// rendered only once per snapshot, so once per content version; an empty
+// key disables memoization, for responses depending on arbitrary input.
+func renderJSON(s *snapshot, key string, lastModified time.Time, build func() (any, error)) (*renderedResponse, error) {
+	if key != "" && s != nil {
+		if cached, ok := s.responses.Load(key); ok {
+			responseHits.Add(1)
+			return cached.(*renderedResponse), nil
+		}
+		responseMisses.Add(1)
+	}
+
+	value, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	response := newRenderedResponse(body, "application/json", lastModified)
+	if key != "" && s != nil {
+		response.memoized = true
+		cached, _ := s.responses.LoadOrStore(key, response)
+		response = cached.(*renderedResponse)
+	}
+
+	return response, nil
+}
+
+// writeRendered writes a rendered response with its validators and the
+// Cache-Control configured for the route, answering 304 Not Modified to
+// conditional requests it satisfies. The body is compressed with the best
+// content coding accepted by the client.
+func writeRendered(w http.ResponseWriter, r *http.Request, route string, response *renderedResponse) {
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if len(response.body) < compressionMinSize {
+		encoding = ""
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if !response.lastModified.IsZero() {
+		header.Set("Last-Modified", response.lastModified.Format(http.TimeFormat))
+	}
+	if getPreviewClaims(r) != nil {
+		// previews must never reach shared caches
+		header.Set("Cache-Control", "private, no-store")
+	} else if cacheControl := getCacheControl(route); cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+
+	if isNotModified(r, response, encoding) {
+		header.Set("ETag", response.variantETag(encoding))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, encoding := response.encoded(encoding)
+	header.Set("ETag", response.variantETag(encoding))
+	header.Set("Content-Type", response.contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+
+	w.Write(body)
+}
+
+// variantETag returns the ETag of the response encoded with the given
+// content coding, each encoding being a different representation.
+func (r *renderedResponse) variantETag(encoding string) string {
+	if encoding == "" {
+		return r.etag
+	}
+
+	return strings.TrimSuffix(r.etag, `"`) + "-" + encoding + `"`
+}
+
+// isNotModified evaluates the conditional headers of a request as RFC 9110
+// prescribes: If-Modified-Since is ignored when If-None-Match is present.
+// Both the identity and the encoded entity tags match.
+func isNotModified(r *http.Request, response *renderedResponse, encoding string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, etag := range strings.Split(inm, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == response.etag || etag == response.variantETag(encoding) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !response.lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err == nil && !response.lastModified.After(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCacheControl returns the Cache-Control configured for a route, or the
+// default one. Without configuration clients must revalidate each time.
+func getCacheControl(route string) string {
+	if cacheControl, ok := settings.Cnf.CacheControl[route]; ok {
+		return cacheControl
+	}
+
+	if cacheControl, ok := settings.Cnf.CacheControl["default"]; ok {
+		return cacheControl
+	}
+
+	return "no-cache"
+}
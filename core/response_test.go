@@ -0,0 +1,111 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRenderJSON(t *testing.T) {
+	s := newSnapshot(nil, "test", nil)
+
+	builds := 0
+	build := func() (any, error) {
+		builds++
+		return map[string]string{"Id": "docs"}, nil
+	}
+
+	lastModified := time.Date(2024, 6, 1, 12, 0, 0, 500, time.UTC)
+	first, err := renderJSON(s, "repo:docs", lastModified, build)
+	if err != nil {
+		t.Fatalf("renderJSON() failed: %v", err)
+	}
+	second, err := renderJSON(s, "repo:docs", lastModified, build)
+	if err != nil {
+		t.Fatalf("renderJSON() failed: %v", err)
+	}
+	if builds != 1 || first != second {
+		t.Errorf("a memoized response was built %d times", builds)
+	}
+
+	if _, err := renderJSON(s, "", lastModified, build); err != nil || builds != 2 {
+		t.Errorf("a response without a key was not built again: %v", err)
+	}
+
+	if string(first.body) != `{"Id":"docs"}` || first.contentType != "application/json" {
+		t.Errorf("response = %s, %s", first.body, first.contentType)
+	}
+	if !first.lastModified.Equal(lastModified.Truncate(time.Second)) {
+		t.Errorf("lastModified = %v, want it truncated to the second", first.lastModified)
+	}
+
+	other := newRenderedResponse([]byte(`{"Id":"other"}`), "application/json", lastModified)
+	if len(first.etag) != 34 || first.etag == other.etag {
+		t.Errorf("ETags %s and %s do not identify the bodies", first.etag, other.etag)
+	}
+}
+
+func TestWriteRenderedConditional(t *testing.T) {
+	lastModified := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	response := newRenderedResponse([]byte(`{"Id":"docs"}`), "application/json", lastModified)
+
+	tests := []struct {
+		name            string
+		method          string
+		ifNoneMatch     string
+		ifModifiedSince time.Time
+		want            int
+	}{
+		{"unconditional", http.MethodGet, "", time.Time{}, http.StatusOK},
+		{"matching ETag", http.MethodGet, response.etag, time.Time{}, http.StatusNotModified},
+		{"matching weak ETag in a list", http.MethodGet, `"other", W/` + response.etag, time.Time{}, http.StatusNotModified},
+		{"any ETag", http.MethodGet, "*", time.Time{}, http.StatusNotModified},
+		{"other ETag", http.MethodGet, `"other"`, time.Time{}, http.StatusOK},
+		{"head", http.MethodHead, response.etag, time.Time{}, http.StatusNotModified},
+		{"post", http.MethodPost, response.etag, time.Time{}, http.StatusOK},
+		{"not modified since", http.MethodGet, "", lastModified, http.StatusNotModified},
+		{"modified since", http.MethodGet, "", lastModified.Add(-time.Second), http.StatusOK},
+		// If-Modified-Since is ignored along with If-None-Match
+		{"other ETag not modified since", http.MethodGet, `"other"`, lastModified, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/docs", nil)
+		if test.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		if !test.ifModifiedSince.IsZero() {
+			r.Header.Set("If-Modified-Since", test.ifModifiedSince.Format(http.TimeFormat))
+		}
+		w := httptest.NewRecorder()
+
+		writeRendered(w, r, "repo", response)
+		if w.Code != test.want {
+			t.Errorf("%s: status = %d, want %d", test.name, w.Code, test.want)
+		}
+		if etag := w.Header().Get("ETag"); etag != response.etag {
+			t.Errorf("%s: ETag = %s, want %s", test.name, etag, response.etag)
+		}
+		if w.Header().Get("Last-Modified") != lastModified.Format(http.TimeFormat) {
+			t.Errorf("%s: Last-Modified = %s", test.name, w.Header().Get("Last-Modified"))
+		}
+
+		body := w.Body.String()
+		if test.want == http.StatusNotModified && body != "" {
+			t.Errorf("%s: 304 response with a body: %s", test.name, body)
+		}
+		if test.want == http.StatusOK && body != string(response.body) {
+			t.Errorf("%s: body = %s, want %s", test.name, body, response.body)
+		}
+	}
+}
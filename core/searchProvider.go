@@ -15,15 +15,16 @@ import (
 	"github.com/vanilla-os/Chronos/structs"
 )
 
-func searchArticles(repoId string, lang string, query string) []structs.Article {
+// searchArticles returns the listed articles matching query.
+func searchArticles(s *snapshot, filter articleFilter, repoId string, lang string, query string) []structs.Article {
 	var results []structs.Article
-	repo, err := getRepo(repoId)
+	repo, err := s.getRepo(repoId)
 	if err != nil {
 		return results
 	}
 
 	for _, article := range repo.Articles {
-		if article.Language == lang {
+		if article.Language == lang && filter.listed(&article) {
 			results = append(results, article)
 		}
 	}
@@ -31,8 +32,14 @@ func searchArticles(repoId string, lang string, query string) []structs.Article
 	return filterByMatch(query, results)
 }
 
-func searchArticle(repoId string, lang string, query string) (structs.Article, bool) {
-	articles := searchArticles(repoId, lang, query)
+// searchArticle returns the article whose slug is query, or else the best
+// listed match. An article hidden by the filter is not replaced by a match.
+func searchArticle(s *snapshot, filter articleFilter, repoId string, lang string, query string) (structs.Article, bool) {
+	if article := s.getArticle(repoId, lang, query); article != nil {
+		return *article, filter.reachable(article)
+	}
+
+	articles := searchArticles(s, filter, repoId, lang, query)
 	if len(articles) > 0 {
 		return articles[0], true
 	}
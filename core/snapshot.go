@@ -0,0 +1,223 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// snapshot is an immutable view of the parsed repositories, indexed for
+// constant time lookups. It is built once per reload and swapped as a whole,
+// so handlers never see a partially loaded state. Nothing reachable from a
+// snapshot must be modified after it has been published.
+type snapshot struct {
+	version      string
+	createdAt    time.Time
+	repos        []*structs.Repo
+	byId         map[string]*structs.Repo
+	articles     map[string]map[string]map[string]*structs.Article // repo ID -> lang -> slug
+	lastModified map[string]time.Time                              // repo ID -> publication of its last change
+	digests      map[string]string                                 // repo ID -> digest of its content
+	schedules    map[string][]time.Time                            // repo ID -> visibility changes, see articleSchedule
+	responses    sync.Map                                          // rendered responses, see renderJSON
+	translations sync.Map                                          // translation indexes, see getTranslations
+}
+
+var currentSnapshot atomic.Pointer[snapshot]
+
+// newSnapshot indexes the given repositories by ID, language and slug. The
+// repositories whose content did not change since the previous snapshot, if
+// any, keep its modification time, the others are modified now.
+func newSnapshot(repos []structs.Repo, version string, previous *snapshot) *snapshot {
+	s := &snapshot{
+		version:      version,
+		createdAt:    time.Now().UTC().Truncate(time.Second),
+		repos:        make([]*structs.Repo, len(repos)),
+		byId:         make(map[string]*structs.Repo, len(repos)),
+		articles:     make(map[string]map[string]map[string]*structs.Article, len(repos)),
+		lastModified: make(map[string]time.Time, len(repos)),
+		digests:      make(map[string]string, len(repos)),
+		schedules:    make(map[string][]time.Time, len(repos)),
+	}
+
+	for i := range repos {
+		repo := &repos[i]
+		s.repos[i] = repo
+		s.byId[repo.Id] = repo
+
+		// commit dates are not used, since rebased commits keep their
+		// original date and deletions do not show in the articles
+		digest, _ := contentVersion([]structs.Repo{*repo})
+		s.digests[repo.Id] = digest
+		s.lastModified[repo.Id] = s.createdAt
+		if previous != nil && previous.digests[repo.Id] == digest {
+			s.lastModified[repo.Id] = previous.lastModified[repo.Id]
+		}
+		s.schedules[repo.Id] = articleSchedule(repo)
+
+		langs := make(map[string]map[string]*structs.Article, len(repo.ArticlesGrouped))
+		for lang, articles := range repo.ArticlesGrouped {
+			slugs := make(map[string]*structs.Article, len(articles))
+			for j := range articles {
+				slugs[articles[j].Slug] = &articles[j]
+			}
+
+			langs[lang] = slugs
+		}
+
+		s.articles[repo.Id] = langs
+	}
+
+	return s
+}
+
+// publishSnapshot makes the given repositories the ones served by Chronos.
+// The current snapshot is kept when the content did not change, along with
+// the responses it rendered. The local cache tier is cleared on each reload,
+// so that no entry of the previous content is read back from it.
+func publishSnapshot(repos []structs.Repo, version string) {
+	clearLocalCache(context.Background())
+
+	s := getSnapshot()
+	if s != nil && s.version == version {
+		return
+	}
+
+	currentSnapshot.Store(newSnapshot(repos, version, s))
+}
+
+// republishSnapshot replaces the current snapshot by a copy without the
+// responses it rendered, so that they are rendered again.
+func republishSnapshot() {
+	s := getSnapshot()
+	if s == nil {
+		return
+	}
+
+	repos := make([]structs.Repo, len(s.repos))
+	for i, repo := range s.repos {
+		repos[i] = *repo
+	}
+
+	currentSnapshot.CompareAndSwap(s, newSnapshot(repos, s.version, s))
+}
+
+// contentVersion returns a digest of the given repositories, identifying
+// their content across reloads and instances.
+func contentVersion(repos []structs.Repo) (string, error) {
+	data, err := json.Marshal(repos)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// getSnapshot returns the snapshot currently served, nil if the repositories
+// were never loaded.
+func getSnapshot() *snapshot {
+	return currentSnapshot.Load()
+}
+
+// getRepo returns the repository with the given ID, which is shared and
+// must not be modified.
+func (s *snapshot) getRepo(repoId string) (*structs.Repo, error) {
+	if s == nil {
+		return nil, errors.New("repos not loaded")
+	}
+
+	repo, ok := s.byId[repoId]
+	if !ok {
+		return nil, errors.New("repo not found")
+	}
+
+	return repo, nil
+}
+
+// getArticle returns the article with the given slug, nil if there is none.
+func (s *snapshot) getArticle(repoId string, lang string, slug string) *structs.Article {
+	if s == nil {
+		return nil
+	}
+
+	return s.articles[repoId][lang][slug]
+}
+
+// getLastModified returns the time the last change to the given repository,
+// or to any of them when repoId is empty, was published.
+func (s *snapshot) getLastModified(repoId string) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+
+	if repoId != "" {
+		return s.lastModified[repoId]
+	}
+
+	var lastModified time.Time
+	for _, t := range s.lastModified {
+		if t.After(lastModified) {
+			lastModified = t
+		}
+	}
+
+	return lastModified
+}
+
+// getTranslations returns the articles of a language of a repository
+// indexed by translationsByKey for the given filter. Indexes are built once
+// per visibility epoch, the articles reachable by a filter only changing
+// with it.
+func (s *snapshot) getTranslations(filter articleFilter, repo *structs.Repo, lang string) map[string]*structs.Article {
+	key := fmt.Sprintf("%s:%s:%t@%d", repo.Id, lang, filter.preview, s.getVisibilityEpoch(repo.Id, filter.now).UnixNano())
+	if translations, ok := s.translations.Load(key); ok {
+		return translations.(map[string]*structs.Article)
+	}
+
+	translations, _ := s.translations.LoadOrStore(key, translationsByKey(filter, repo.ArticlesGrouped[lang]))
+	return translations.(map[string]*structs.Article)
+}
+
+// getVisibilityEpoch returns the last time before now the visibility of an
+// article changed in the given repository, or in all of them when repoId is
+// empty. It is zero when none did.
+func (s *snapshot) getVisibilityEpoch(repoId string, now time.Time) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+
+	var epoch time.Time
+	for id, schedule := range s.schedules {
+		if repoId != "" && id != repoId {
+			continue
+		}
+
+		i := sort.Search(len(schedule), func(i int) bool {
+			return schedule[i].After(now)
+		})
+		if i > 0 && schedule[i-1].After(epoch) {
+			epoch = schedule[i-1]
+		}
+	}
+
+	return epoch
+}
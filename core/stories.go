@@ -0,0 +1,254 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// storyChapters returns the slugs of the chapters of a story in a language,
+// in reading order: those of its chapters list when it has one, or else
+// those linked by the Next header of the articles from StartSlug. The
+// problems found on the way, broken links, cycles and articles of the story
+// which are not among its chapters, are returned as well.
+func storyChapters(repo *structs.Repo, lang string, story structs.Story) ([]string, []string) {
+	story = localizeStory(story, lang)
+
+	articles := make(map[string]*structs.Article)
+	for i, article := range repo.ArticlesGrouped[lang] {
+		if article.StoryId == story.Id {
+			articles[article.Slug] = &repo.ArticlesGrouped[lang][i]
+		}
+	}
+	if len(articles) == 0 {
+		return nil, nil // not available in this language
+	}
+
+	var chapters []string
+	var problems []string
+	seen := make(map[string]bool)
+
+	if len(story.Chapters) > 0 {
+		for _, slug := range story.Chapters {
+			switch {
+			case articles[slug] == nil:
+				problems = append(problems, fmt.Sprintf("chapter %s is not an article of the story", slug))
+			case seen[slug]:
+				problems = append(problems, fmt.Sprintf("chapter %s is listed twice", slug))
+			default:
+				seen[slug] = true
+				chapters = append(chapters, slug)
+			}
+		}
+	} else {
+		if story.StartSlug == "" {
+			problems = append(problems, "no startSlug nor chapters")
+		}
+
+		for slug, previous := story.StartSlug, ""; slug != ""; {
+			article := articles[slug]
+			if article == nil {
+				problems = append(problems, fmt.Sprintf("%s is not an article of the story", slug))
+				break
+			}
+			if seen[slug] {
+				problems = append(problems, fmt.Sprintf("%s links back to %s, making a cycle", previous, slug))
+				break
+			}
+			if article.Previous != previous {
+				problems = append(problems, fmt.Sprintf("%s has %q as previous chapter instead of %q", slug, article.Previous, previous))
+			}
+
+			seen[slug] = true
+			chapters = append(chapters, slug)
+			slug, previous = article.Next, slug
+		}
+	}
+
+	var orphans []string
+	for slug := range articles {
+		if !seen[slug] {
+			orphans = append(orphans, slug)
+		}
+	}
+	sort.Strings(orphans)
+	for _, slug := range orphans {
+		problems = append(problems, fmt.Sprintf("%s is not reachable from the other chapters", slug))
+	}
+
+	return chapters, problems
+}
+
+// checkStories logs the problems of the stories of a repository.
+func checkStories(repo *structs.Repo) {
+	for _, story := range repo.Stories {
+		for _, lang := range repo.Languages {
+			_, problems := storyChapters(repo, lang, story)
+			for _, problem := range problems {
+				log.Printf("(loader): Story %s of %s in %s: %s\n", story.Id, repo.Id, lang, problem)
+			}
+		}
+	}
+}
+
+// newStoryResponse returns a story with its chapters visible to the filter,
+// numbered in reading order. It is nil when the story has no chapters in
+// the given language.
+func newStoryResponse(s *snapshot, filter articleFilter, repo *structs.Repo, lang string, story structs.Story) *structs.StoryResponse {
+	slugs, _ := storyChapters(repo, lang, story)
+
+	chapters := make([]structs.StoryChapter, 0, len(slugs))
+	for _, slug := range slugs {
+		article := s.getArticle(repo.Id, lang, slug)
+		if article == nil || !filter.reachable(article) {
+			continue
+		}
+
+		chapters = append(chapters, structs.StoryChapter{
+			Number:      len(chapters) + 1,
+			Slug:        article.Slug,
+			Title:       article.Title,
+			Description: article.Description,
+		})
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	story = localizeStory(story, lang)
+	return &structs.StoryResponse{
+		Id:          story.Id,
+		Name:        story.Name,
+		Description: story.Description,
+		Chapters:    chapters,
+	}
+}
+
+// localizeStory returns a story in the given language. Its name and
+// description are taken from the first language of the fallback chain
+// translating them, or else are the default ones, while its chapters are
+// only those of the language itself, since they are slugs of its articles.
+// A story without a default name or description, translated in other
+// languages only, falls back to those of its first translation.
+func localizeStory(story structs.Story, lang string) structs.Story {
+	translations := story.Translations
+	story.Translations = nil
+
+	var name, description string
+	for i, candidate := range languageChain(lang) {
+		translation, ok := findStoryTranslation(translations, candidate)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			name = translation.Name
+		}
+		if description == "" {
+			description = translation.Description
+		}
+
+		if i > 0 {
+			continue
+		}
+		if translation.StartSlug != "" {
+			story.StartSlug = translation.StartSlug
+		}
+		if len(translation.Chapters) > 0 {
+			story.Chapters = translation.Chapters
+		}
+	}
+
+	if name != "" {
+		story.Name = name
+	}
+	if description != "" {
+		story.Description = description
+	}
+
+	langs := make([]string, 0, len(translations))
+	for l := range translations {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	for _, l := range langs {
+		if story.Name == "" {
+			story.Name = translations[l].Name
+		}
+		if story.Description == "" {
+			story.Description = translations[l].Description
+		}
+	}
+
+	return story
+}
+
+// findStoryTranslation returns the translation of a story in a language,
+// whose key may have any case or form.
+func findStoryTranslation(translations map[string]structs.StoryTranslation, lang string) (structs.StoryTranslation, bool) {
+	if translation, ok := translations[lang]; ok {
+		return translation, true
+	}
+
+	for l, translation := range translations {
+		if sameLocale(l, lang) {
+			return translation, true
+		}
+	}
+
+	return structs.StoryTranslation{}, false
+}
+
+// localizeStories returns the stories of a repository in the given
+// language.
+func localizeStories(stories map[string]structs.Story, lang string) map[string]structs.Story {
+	if stories == nil {
+		return nil
+	}
+
+	localized := make(map[string]structs.Story, len(stories))
+	for id, story := range stories {
+		localized[id] = localizeStory(story, lang)
+	}
+
+	return localized
+}
+
+// translateStory records the fields of a story found in the stories.yml
+// file of a language as its translation in that language.
+func translateStory(story structs.Story, lang string, translated structs.Story) structs.Story {
+	story.Id = translated.Id
+
+	translation := story.Translations[lang]
+	if translated.Name != "" {
+		translation.Name = translated.Name
+	}
+	if translated.Description != "" {
+		translation.Description = translated.Description
+	}
+	if translated.StartSlug != "" {
+		translation.StartSlug = translated.StartSlug
+	}
+	if len(translated.Chapters) > 0 {
+		translation.Chapters = translated.Chapters
+	}
+
+	if story.Translations == nil {
+		story.Translations = make(map[string]structs.StoryTranslation)
+	}
+	story.Translations[lang] = translation
+
+	return story
+}
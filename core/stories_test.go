@@ -0,0 +1,230 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vanilla-os/Chronos/structs"
+	"gopkg.in/yaml.v3"
+)
+
+// storyArticle returns an article of the story "guide".
+func storyArticle(slug string, previous string, next string) structs.Article {
+	return structs.Article{Slug: slug, StoryId: "guide", Previous: previous, Next: next, Language: "en"}
+}
+
+func TestStoryChapters(t *testing.T) {
+	tests := []struct {
+		name     string
+		story    structs.Story
+		articles []structs.Article
+		chapters []string
+		problems []string
+	}{
+		{
+			name:  "linked",
+			story: structs.Story{Id: "guide", StartSlug: "a"},
+			articles: []structs.Article{
+				storyArticle("c", "b", ""),
+				storyArticle("a", "", "b"),
+				storyArticle("b", "a", "c"),
+				{Slug: "other", Language: "en"},
+			},
+			chapters: []string{"a", "b", "c"},
+		},
+		{
+			name:  "chapters list",
+			story: structs.Story{Id: "guide", Chapters: []string{"c", "a", "b"}},
+			articles: []structs.Article{
+				storyArticle("a", "", ""),
+				storyArticle("b", "", ""),
+				storyArticle("c", "", ""),
+			},
+			chapters: []string{"c", "a", "b"},
+		},
+		{
+			name:  "broken link",
+			story: structs.Story{Id: "guide", StartSlug: "a"},
+			articles: []structs.Article{
+				storyArticle("a", "", "missing"),
+				storyArticle("b", "", ""),
+			},
+			chapters: []string{"a"},
+			problems: []string{
+				"missing is not an article of the story",
+				"b is not reachable from the other chapters",
+			},
+		},
+		{
+			name:  "cycle",
+			story: structs.Story{Id: "guide", StartSlug: "a"},
+			articles: []structs.Article{
+				storyArticle("a", "b", "b"),
+				storyArticle("b", "a", "a"),
+			},
+			chapters: []string{"a", "b"},
+			problems: []string{
+				`a has "b" as previous chapter instead of ""`,
+				"b links back to a, making a cycle",
+			},
+		},
+		{
+			name:  "orphans",
+			story: structs.Story{Id: "guide", StartSlug: "a"},
+			articles: []structs.Article{
+				storyArticle("a", "", ""),
+				storyArticle("c", "b", ""),
+				storyArticle("b", "", "c"),
+			},
+			chapters: []string{"a"},
+			problems: []string{
+				"b is not reachable from the other chapters",
+				"c is not reachable from the other chapters",
+			},
+		},
+		{
+			name:  "invalid chapters list",
+			story: structs.Story{Id: "guide", Chapters: []string{"a", "missing", "a"}},
+			articles: []structs.Article{
+				storyArticle("a", "", ""),
+			},
+			chapters: []string{"a"},
+			problems: []string{
+				"chapter missing is not an article of the story",
+				"chapter a is listed twice",
+			},
+		},
+		{
+			name:  "no start",
+			story: structs.Story{Id: "guide"},
+			articles: []structs.Article{
+				storyArticle("a", "", ""),
+			},
+			problems: []string{
+				"no startSlug nor chapters",
+				"a is not reachable from the other chapters",
+			},
+		},
+		{
+			name:     "not translated",
+			story:    structs.Story{Id: "guide", StartSlug: "a"},
+			articles: []structs.Article{{Slug: "a", Language: "en"}},
+		},
+	}
+
+	for _, test := range tests {
+		repo := &structs.Repo{
+			Id:              "docs",
+			ArticlesGrouped: map[string][]structs.Article{"en": test.articles},
+		}
+
+		chapters, problems := storyChapters(repo, "en", test.story)
+		if !reflect.DeepEqual(chapters, test.chapters) {
+			t.Errorf("%s: chapters = %q, want %q", test.name, chapters, test.chapters)
+		}
+		if !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: problems = %q, want %q", test.name, problems, test.problems)
+		}
+	}
+}
+
+func TestNewStoryResponse(t *testing.T) {
+	draft := storyArticle("b", "a", "c")
+	draft.Draft = true
+
+	repo := structs.Repo{
+		Id:        "docs",
+		Languages: []string{"en"},
+		ArticlesGrouped: map[string][]structs.Article{"en": {
+			storyArticle("a", "", "b"),
+			draft,
+			storyArticle("c", "b", ""),
+		}},
+	}
+	s := newSnapshot([]structs.Repo{repo}, "test", nil)
+	story := structs.Story{Id: "guide", Name: "Guide", StartSlug: "a"}
+
+	// hidden chapters are left out of the numbering
+	response := newStoryResponse(s, articleFilter{}, &repo, "en", story)
+	if response == nil {
+		t.Fatal("newStoryResponse() = nil, want the published chapters")
+	}
+	if len(response.Chapters) != 2 || response.Chapters[1].Slug != "c" || response.Chapters[1].Number != 2 {
+		t.Errorf("chapters = %+v, want a and c numbered 1 and 2", response.Chapters)
+	}
+
+	response = newStoryResponse(s, articleFilter{preview: true}, &repo, "en", story)
+	if response == nil || len(response.Chapters) != 3 {
+		t.Errorf("chapters in a preview = %+v, want all of them", response)
+	}
+
+	if response := newStoryResponse(s, articleFilter{}, &repo, "it", story); response != nil {
+		t.Errorf("newStoryResponse() in a missing language = %+v, want nil", response)
+	}
+}
+
+func TestLocalizeStory(t *testing.T) {
+	setLanguageSettings(t, "en", nil)
+
+	var story structs.Story
+	err := yaml.Unmarshal([]byte(`
+id: guide
+name:
+  en: Guide
+  pt: Guia
+  it: Guida
+description: The guide
+startSlug: a
+`), &story)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() failed: %v", err)
+	}
+	story = translateStory(story, "pt-BR", structs.Story{Id: "guide", Chapters: []string{"b", "a"}})
+	story = translateStory(story, "pt", structs.Story{Id: "guide", StartSlug: "c"})
+
+	var untitled structs.Story
+	err = yaml.Unmarshal([]byte("id: guide\nname: {pt: Guia, it: Guida}\nstartSlug: a\n"), &untitled)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		story     structs.Story
+		lang      string
+		want      string
+		desc      string
+		startSlug string
+		chapters  []string
+	}{
+		{"translated", story, "it", "Guida", "The guide", "a", nil},
+		{"default", story, "fr", "Guide", "The guide", "a", nil},
+		// the chapters are those of the language, the name those of pt
+		{"parent language", story, "pt-BR", "Guia", "The guide", "a", []string{"b", "a"}},
+		{"other form", story, "pt_br", "Guia", "The guide", "a", []string{"b", "a"}},
+		{"own chapters", story, "pt", "Guia", "The guide", "c", nil},
+		{"no default name", untitled, "de", "Guida", "", "a", nil},
+	}
+
+	for _, test := range tests {
+		localized := localizeStory(test.story, test.lang)
+		if localized.Name != test.want || localized.Description != test.desc {
+			t.Errorf("%s: name = %q, description = %q, want %q, %q", test.name, localized.Name, localized.Description, test.want, test.desc)
+		}
+		if localized.StartSlug != test.startSlug || !reflect.DeepEqual(localized.Chapters, test.chapters) {
+			t.Errorf("%s: startSlug = %q, chapters = %q, want %q, %q", test.name, localized.StartSlug, localized.Chapters, test.startSlug, test.chapters)
+		}
+		if localized.Translations != nil {
+			t.Errorf("%s: translations were not removed", test.name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"sort"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// articleTerms returns the terms an article is classified by, its tags or
+// its authors.
+type articleTerms func(article *structs.Article) []string
+
+func articleTags(article *structs.Article) []string {
+	return article.Tags
+}
+
+func articleAuthors(article *structs.Article) []string {
+	return article.Authors
+}
+
+// countTerms returns the sorted terms of the given articles, along with the
+// number of articles of each one.
+func countTerms(articles []structs.Article, terms articleTerms) ([]string, map[string]int) {
+	counts := make(map[string]int)
+	for i := range articles {
+		for _, term := range terms(&articles[i]) {
+			counts[term]++
+		}
+	}
+
+	sorted := make([]string, 0, len(counts))
+	for term := range counts {
+		sorted = append(sorted, term)
+	}
+	sort.Strings(sorted)
+
+	return sorted, counts
+}
+
+// articlesWithTerm returns the articles classified by the given term.
+func articlesWithTerm(articles []structs.Article, terms articleTerms, term string) []structs.Article {
+	var matching []structs.Article
+	for i := range articles {
+		for _, t := range terms(&articles[i]) {
+			if t == term {
+				matching = append(matching, articles[i])
+				break
+			}
+		}
+	}
+
+	return matching
+}
+
+// getTag returns the description of a tag, named after its ID when the
+// repository does not describe it.
+func getTag(repo *structs.Repo, id string) structs.Tag {
+	tag, ok := repo.Tags[id]
+	if !ok {
+		tag.Id = id
+	}
+	if tag.Name == "" {
+		tag.Name = id
+	}
+
+	return tag
+}
+
+// getAuthor returns the profile of an author, named after their ID when the
+// repository does not describe them.
+func getAuthor(repo *structs.Repo, id string) structs.Author {
+	author, ok := repo.Authors[id]
+	if !ok {
+		author.Id = id
+	}
+	if author.Name == "" {
+		author.Name = id
+	}
+
+	return author
+}
+
+// termKind is a way of classifying articles, by tag or by author, along with
+// the shapes of its responses.
+type termKind struct {
+	name     string // route name of a term, its plural naming the list
+	terms    articleTerms
+	summary  func(repo *structs.Repo, id string, count int) any
+	response func(summary any, page structs.ArticlesPage) any
+}
+
+var tagKind = termKind{
+	name:  "tag",
+	terms: articleTags,
+	summary: func(repo *structs.Repo, id string, count int) any {
+		return structs.TagSummary{Tag: getTag(repo, id), Count: count}
+	},
+	response: func(summary any, page structs.ArticlesPage) any {
+		return structs.TagResponse{Tag: summary.(structs.TagSummary), ArticlesPage: page}
+	},
+}
+
+var authorKind = termKind{
+	name:  "author",
+	terms: articleAuthors,
+	summary: func(repo *structs.Repo, id string, count int) any {
+		return structs.AuthorSummary{Author: getAuthor(repo, id), Count: count}
+	},
+	response: func(summary any, page structs.ArticlesPage) any {
+		return structs.AuthorResponse{Author: summary.(structs.AuthorSummary), ArticlesPage: page}
+	},
+}
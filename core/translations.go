@@ -0,0 +1,176 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// minSourceHashLength is the length a SourceHash marker can be abbreviated
+// to.
+const minSourceHashLength = 8
+
+// translationKey returns the key matching an article with its translations,
+// its TranslationKey or else its slug.
+func translationKey(article *structs.Article) string {
+	if article.TranslationKey != "" {
+		return article.TranslationKey
+	}
+
+	return article.Slug
+}
+
+// translationsByKey indexes the reachable articles of a language by their
+// translation key. An explicit TranslationKey wins over a slug, then the
+// first article by path.
+func translationsByKey(filter articleFilter, articles []structs.Article) map[string]*structs.Article {
+	translations := make(map[string]*structs.Article, len(articles))
+	for i := range articles {
+		if !filter.reachable(&articles[i]) {
+			continue
+		}
+
+		key := translationKey(&articles[i])
+		if other, ok := translations[key]; ok && (other.TranslationKey != "" || articles[i].TranslationKey == "") {
+			continue
+		}
+
+		translations[key] = &articles[i]
+	}
+
+	return translations
+}
+
+// matchesSourceHash reports whether the SourceHash marker of a translation,
+// possibly abbreviated, is the ContentHash of its source.
+func matchesSourceHash(contentHash string, marker string) bool {
+	return len(marker) >= minSourceHashLength && strings.HasPrefix(contentHash, marker)
+}
+
+// newTranslationStatus compares a translation, nil if missing, with its
+// source article. The SourceHash marker of the translation, when set, takes
+// precedence over the Git history.
+func newTranslationStatus(repo *structs.Repo, source *structs.Article, translation *structs.Article) structs.TranslationStatus {
+	status := structs.TranslationStatus{
+		Key:            translationKey(source),
+		SourceSlug:     source.Slug,
+		Status:         structs.TranslationMissing,
+		SourceModified: source.LastModified,
+	}
+	if translation == nil {
+		return status
+	}
+
+	status.Slug = translation.Slug
+	status.Status = structs.TranslationUpToDate
+	status.Modified = translation.LastModified
+
+	if translation.SourceHash != "" {
+		if !matchesSourceHash(source.ContentHash, translation.SourceHash) {
+			status.Status = structs.TranslationOutdated
+			status.OutdatedBy = structs.OutdatedSourceHash
+		}
+
+		return status
+	}
+
+	if translation.LastModified.IsZero() {
+		return status
+	}
+
+	// the history is sorted newest first
+	for _, commit := range repo.History[source.Path] {
+		if !commit.Date.After(translation.LastModified) {
+			break
+		}
+		status.SourceCommits++
+	}
+	if status.SourceCommits > 0 {
+		status.Status = structs.TranslationOutdated
+		status.OutdatedBy = structs.OutdatedSourceCommits
+	}
+
+	return status
+}
+
+// newTranslationsResponse reports the translation status of the articles of
+// a repository in the source language, in each of its other languages.
+func newTranslationsResponse(s *snapshot, filter articleFilter, repo *structs.Repo, source string) structs.TranslationsResponse {
+	sources := s.getTranslations(filter, repo, source)
+
+	// keep the order of the articles, by path
+	var sourceArticles []*structs.Article
+	for i := range repo.ArticlesGrouped[source] {
+		article := &repo.ArticlesGrouped[source][i]
+		if sources[translationKey(article)] == article {
+			sourceArticles = append(sourceArticles, article)
+		}
+	}
+
+	response := structs.TranslationsResponse{
+		SourceLanguage: source,
+		Total:          len(sourceArticles),
+		Languages:      []structs.LanguageCoverage{},
+	}
+
+	for _, lang := range repo.Languages {
+		if lang == source {
+			continue
+		}
+
+		translations := s.getTranslations(filter, repo, lang)
+		coverage := structs.LanguageCoverage{
+			Language: lang,
+			Articles: make([]structs.TranslationStatus, 0, len(sourceArticles)),
+		}
+
+		for _, article := range sourceArticles {
+			status := newTranslationStatus(repo, article, translations[translationKey(article)])
+			switch status.Status {
+			case structs.TranslationMissing:
+				coverage.Missing++
+			case structs.TranslationOutdated:
+				coverage.Outdated++
+			default:
+				coverage.UpToDate++
+			}
+
+			coverage.Articles = append(coverage.Articles, status)
+		}
+
+		response.Languages = append(response.Languages, coverage)
+	}
+
+	return response
+}
+
+// articleAlternates returns the URLs of the reachable translations of an
+// article, the article itself included, by BCP 47 language tag.
+func articleAlternates(s *snapshot, filter articleFilter, repo *structs.Repo, article *structs.Article) map[string]string {
+	key := translationKey(article)
+
+	alternates := make(map[string]string)
+	for _, lang := range repo.Languages {
+		translation, ok := s.getTranslations(filter, repo, lang)[key]
+		if lang == article.Language {
+			translation, ok = article, true
+		}
+		if !ok {
+			continue
+		}
+
+		alternates[canonicalLocale(lang)] = fmt.Sprintf("/%s/articles/%s/%s", repo.Id, lang, translation.Slug)
+	}
+
+	return alternates
+}
@@ -0,0 +1,190 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+func TestNewTranslationStatus(t *testing.T) {
+	translated := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &structs.Repo{
+		Id: "docs",
+		History: map[string][]structs.ArticleCommit{
+			"en/a.md": {
+				{Hash: "3", Date: translated.Add(48 * time.Hour)},
+				{Hash: "2", Date: translated.Add(24 * time.Hour)},
+				{Hash: "1", Date: translated},
+				{Hash: "0", Date: translated.Add(-24 * time.Hour)},
+			},
+		},
+	}
+	source := &structs.Article{
+		Slug:         "a",
+		Path:         "en/a.md",
+		ContentHash:  "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		LastModified: translated.Add(48 * time.Hour),
+	}
+
+	tests := []struct {
+		name        string
+		translation *structs.Article
+		status      string
+		outdatedBy  string
+		commits     int
+	}{
+		{"missing", nil, structs.TranslationMissing, "", 0},
+		{"source hash", &structs.Article{SourceHash: source.ContentHash}, structs.TranslationUpToDate, "", 0},
+		{"abbreviated source hash", &structs.Article{SourceHash: "01234567"}, structs.TranslationUpToDate, "", 0},
+		{"too short source hash", &structs.Article{SourceHash: "0123"}, structs.TranslationOutdated, structs.OutdatedSourceHash, 0},
+		{"other source hash", &structs.Article{SourceHash: "fedcba98"}, structs.TranslationOutdated, structs.OutdatedSourceHash, 0},
+		// the marker wins over the history
+		{"source hash of an older translation", &structs.Article{SourceHash: "01234567", LastModified: translated}, structs.TranslationUpToDate, "", 0},
+		{"newer source commits", &structs.Article{LastModified: translated}, structs.TranslationOutdated, structs.OutdatedSourceCommits, 2},
+		{"no newer source commits", &structs.Article{LastModified: translated.Add(72 * time.Hour)}, structs.TranslationUpToDate, "", 0},
+		{"no history", &structs.Article{}, structs.TranslationUpToDate, "", 0},
+	}
+
+	for _, test := range tests {
+		status := newTranslationStatus(repo, source, test.translation)
+		if status.Status != test.status || status.OutdatedBy != test.outdatedBy || status.SourceCommits != test.commits {
+			t.Errorf("%s: status = %s, outdated by %q with %d commits, want %s, outdated by %q with %d commits",
+				test.name, status.Status, status.OutdatedBy, status.SourceCommits, test.status, test.outdatedBy, test.commits)
+		}
+		if status.Key != "a" || status.SourceSlug != "a" || !status.SourceModified.Equal(source.LastModified) {
+			t.Errorf("%s: source of the status = %+v", test.name, status)
+		}
+	}
+}
+
+func TestTranslationsByKey(t *testing.T) {
+	articles := []structs.Article{
+		{Slug: "a", Path: "it/a.md"},
+		{Slug: "guida", Path: "it/guida.md", TranslationKey: "guide"},
+		{Slug: "guide", Path: "it/guide.md"},
+		{Slug: "bozza", Path: "it/bozza.md", TranslationKey: "draft", Draft: true},
+		{Slug: "b", Path: "it/b.md", TranslationKey: "a"},
+	}
+
+	translations := translationsByKey(articleFilter{now: time.Now()}, articles)
+	for key, want := range map[string]string{"a": "b", "guide": "guida"} {
+		if got := translations[key]; got == nil || got.Slug != want {
+			t.Errorf("translation %s = %+v, want %s", key, got, want)
+		}
+	}
+	if _, ok := translations["draft"]; ok {
+		t.Error("an unpublished translation was indexed")
+	}
+	if len(translations) != 2 {
+		t.Errorf("%d translations were indexed, want 2", len(translations))
+	}
+
+	previews := translationsByKey(articleFilter{preview: true}, articles)
+	if previews["draft"] == nil {
+		t.Error("an unpublished translation was not indexed in a preview")
+	}
+}
+
+func TestGetTranslations(t *testing.T) {
+	articles := []structs.Article{
+		{Slug: "a", Path: "en/a.md", Language: "en"},
+		{Slug: "b", Path: "en/b.md", Language: "en", PublicationDate: "2024-06-01"},
+	}
+	repo := structs.Repo{
+		Id:              "docs",
+		Languages:       []string{"en"},
+		Articles:        map[string]structs.Article{"en/a.md": articles[0], "en/b.md": articles[1]},
+		ArticlesGrouped: map[string][]structs.Article{"en": articles},
+	}
+	s := newSnapshot([]structs.Repo{repo}, "test", nil)
+	published := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// the index is built once per filter and visibility epoch
+	before := articleFilter{now: published.Add(-time.Hour)}
+	index := s.getTranslations(before, s.byId["docs"], "en")
+	if len(index) != 1 {
+		t.Errorf("translations before the publication = %d, want 1", len(index))
+	}
+	s.getTranslations(articleFilter{now: published.Add(-time.Minute)}, s.byId["docs"], "en")
+	indexes := 0
+	s.translations.Range(func(key, value any) bool {
+		indexes++
+		return true
+	})
+	if indexes != 1 {
+		t.Errorf("%d indexes were built within the same visibility epoch, want 1", indexes)
+	}
+
+	if after := s.getTranslations(articleFilter{now: published}, s.byId["docs"], "en"); len(after) != 2 {
+		t.Errorf("translations after the publication = %d, want 2", len(after))
+	}
+	if preview := s.getTranslations(articleFilter{preview: true, now: before.now}, s.byId["docs"], "en"); len(preview) != 2 {
+		t.Errorf("translations in a preview = %d, want 2", len(preview))
+	}
+}
+
+func TestArticleAlternates(t *testing.T) {
+	articles := map[string][]structs.Article{
+		"en":    {{Slug: "guide", Path: "en/guide.md", Language: "en"}, {Slug: "alone", Path: "en/alone.md", Language: "en"}},
+		"it":    {{Slug: "guida", Path: "it/guida.md", Language: "it", TranslationKey: "guide"}},
+		"pt_br": {{Slug: "guia", Path: "pt_br/guia.md", Language: "pt_br", TranslationKey: "guide", Draft: true}},
+	}
+	repo := structs.Repo{
+		Id:              "docs",
+		Languages:       []string{"en", "it", "pt_br"},
+		ArticlesGrouped: articles,
+	}
+	s := newSnapshot([]structs.Repo{repo}, "test", nil)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		filter  articleFilter
+		article *structs.Article
+		want    map[string]string
+	}{
+		{
+			name:    "published",
+			filter:  articleFilter{now: now},
+			article: &articles["it"][0],
+			want: map[string]string{
+				"en": "/docs/articles/en/guide",
+				"it": "/docs/articles/it/guida",
+			},
+		},
+		{
+			name:    "preview",
+			filter:  articleFilter{preview: true, now: now},
+			article: &articles["en"][0],
+			want: map[string]string{
+				"en":    "/docs/articles/en/guide",
+				"it":    "/docs/articles/it/guida",
+				"pt-BR": "/docs/articles/pt_br/guia",
+			},
+		},
+		{
+			name:    "untranslated",
+			filter:  articleFilter{now: now},
+			article: &articles["en"][1],
+			want:    map[string]string{"en": "/docs/articles/en/alone"},
+		},
+	}
+
+	for _, test := range tests {
+		got := articleAlternates(s, test.filter, s.byId["docs"], test.article)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: alternates = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
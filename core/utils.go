@@ -1,42 +1,34 @@
 package core
 
 import (
-	"bufio"
-	"fmt"
-	"log"
-	"os"
-	"strings"
+	"github.com/vanilla-os/Chronos/settings"
 )
 
-func askForConfirmation(s string) bool {
-	reader := bufio.NewReader(os.Stdin)
-
-	for {
-		fmt.Printf("%s [y/n]: ", s)
-
-		response, err := reader.ReadString('\n')
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		response = strings.ToLower(strings.TrimSpace(response))
+// isValidLocale reports whether s is a BCP 47 language tag, such as en,
+// pt-BR, zh-Hans or es-419, of a language known to CLDR.
+func isValidLocale(s string) bool {
+	_, ok := parseLocale(s)
+	return ok
+}
 
-		if response == "y" || response == "yes" {
-			return true
-		} else if response == "n" || response == "no" {
-			return false
-		}
+// getRootPath returns the articles root path of a repository, defaulting to
+// "articles" when none is configured.
+func getRootPath(repo settings.ConfigRepo) string {
+	if repo.RootPath != "" {
+		return repo.RootPath
 	}
+
+	return "articles"
 }
 
-func isValidLocale(s string) bool {
-	// TODO: improve using the package "golang.org/x/text/language"
-	if len(s) != 2 {
+// isSafeDirName reports whether s can be used as is as a directory name.
+func isSafeDirName(s string) bool {
+	if s == "" || s == "." || s == ".." {
 		return false
 	}
 
 	for _, c := range s {
-		if c < 'a' || c > 'z' {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
 			return false
 		}
 	}
@@ -0,0 +1,144 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+// articleDateLayouts are the accepted formats of PublicationDate and
+// ExpiryDate, dates without a time being at midnight UTC.
+var articleDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	time.RFC3339,
+}
+
+// parseArticleDate parses the date of an article header.
+func parseArticleDate(value string) (time.Time, bool) {
+	for _, layout := range articleDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// isArticlePublished reports whether an article is public at the given
+// time: not a draft, with a publication date in the past and an expiry date,
+// if any, in the future. Dates which cannot be parsed are ignored.
+func isArticlePublished(article *structs.Article, now time.Time) bool {
+	if article.Draft {
+		return false
+	}
+
+	if at, ok := parseArticleDate(article.PublicationDate); ok && at.After(now) {
+		return false
+	}
+
+	if at, ok := parseArticleDate(article.ExpiryDate); ok && !at.After(now) {
+		return false
+	}
+
+	return true
+}
+
+// articleFilter decides which articles a request can see.
+type articleFilter struct {
+	preview bool
+	now     time.Time
+}
+
+// newArticleFilter returns the filter of a request for the articles of a
+// repository, which sees unpublished articles when the request carries a
+// preview token for it.
+func newArticleFilter(r *http.Request, repoId string) articleFilter {
+	return articleFilter{
+		preview: getPreviewClaims(r).covers(repoId),
+		now:     time.Now(),
+	}
+}
+
+// reachable reports whether an article can be requested by its slug.
+func (f articleFilter) reachable(article *structs.Article) bool {
+	return f.preview || isArticlePublished(article, f.now)
+}
+
+// listed reports whether an article appears in lists and search results.
+func (f articleFilter) listed(article *structs.Article) bool {
+	return article.Listed && f.reachable(article)
+}
+
+// listedArticles returns the articles appearing in lists.
+func (f articleFilter) listedArticles(articles []structs.Article) []structs.Article {
+	listed := make([]structs.Article, 0, len(articles))
+	for i := range articles {
+		if f.listed(&articles[i]) {
+			listed = append(listed, articles[i])
+		}
+	}
+
+	return listed
+}
+
+// memoKey returns the key a list response is memoized with, which changes
+// each time a scheduled article is published or expires. Previews are not
+// memoized.
+func (f articleFilter) memoKey(s *snapshot, repoId string, key string) string {
+	if f.preview || key == "" {
+		return ""
+	}
+
+	if epoch := s.getVisibilityEpoch(repoId, f.now); !epoch.IsZero() {
+		return fmt.Sprintf("%s@%d", key, epoch.Unix())
+	}
+
+	return key
+}
+
+// lastModified returns the time the lists of a repository last changed,
+// scheduled publications and expirations included.
+func (f articleFilter) lastModified(s *snapshot, repoId string) time.Time {
+	lastModified := s.getLastModified(repoId)
+	if epoch := s.getVisibilityEpoch(repoId, f.now); epoch.After(lastModified) {
+		return epoch
+	}
+
+	return lastModified
+}
+
+// articleSchedule returns the sorted times at which the visibility of the
+// articles of a repository changes.
+func articleSchedule(repo *structs.Repo) []time.Time {
+	var schedule []time.Time
+	for _, article := range repo.Articles {
+		if article.Draft {
+			continue
+		}
+
+		for _, date := range []string{article.PublicationDate, article.ExpiryDate} {
+			if at, ok := parseArticleDate(date); ok {
+				schedule = append(schedule, at)
+			}
+		}
+	}
+
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i].Before(schedule[j])
+	})
+
+	return schedule
+}
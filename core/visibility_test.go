@@ -0,0 +1,98 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vanilla-os/Chronos/structs"
+)
+
+func TestArticleVisibility(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		article   structs.Article
+		published bool
+		listed    bool
+	}{
+		{"public", structs.Article{Listed: true}, true, true},
+		{"unlisted", structs.Article{}, true, false},
+		{"draft", structs.Article{Listed: true, Draft: true}, false, false},
+		{"published", structs.Article{Listed: true, PublicationDate: "2024-06-01"}, true, true},
+		{"scheduled", structs.Article{Listed: true, PublicationDate: "2024-06-01 12:30"}, false, false},
+		{"published in another time zone", structs.Article{Listed: true, PublicationDate: "2024-06-01T13:30:00+02:00"}, true, true},
+		{"expired", structs.Article{Listed: true, ExpiryDate: "2024-06-01T12:00:00Z"}, false, false},
+		{"expiring", structs.Article{Listed: true, ExpiryDate: "2024-06-02"}, true, true},
+		{"invalid dates", structs.Article{Listed: true, PublicationDate: "soon", ExpiryDate: "never"}, true, true},
+	}
+
+	for _, test := range tests {
+		if got := isArticlePublished(&test.article, now); got != test.published {
+			t.Errorf("%s: isArticlePublished() = %t, want %t", test.name, got, test.published)
+		}
+
+		filter := articleFilter{now: now}
+		if got := filter.reachable(&test.article); got != test.published {
+			t.Errorf("%s: reachable() = %t, want %t", test.name, got, test.published)
+		}
+		if got := filter.listed(&test.article); got != test.listed {
+			t.Errorf("%s: listed() = %t, want %t", test.name, got, test.listed)
+		}
+
+		// previews see every article, but lists keep hiding unlisted ones
+		preview := articleFilter{preview: true, now: now}
+		if !preview.reachable(&test.article) {
+			t.Errorf("%s: reachable() in a preview = false, want true", test.name)
+		}
+		if got := preview.listed(&test.article); got != test.article.Listed {
+			t.Errorf("%s: listed() in a preview = %t, want %t", test.name, got, test.article.Listed)
+		}
+	}
+}
+
+func TestHandleArticleVisibility(t *testing.T) {
+	articles := []structs.Article{
+		{Slug: "public", Language: "en", Listed: true},
+		{Slug: "unlisted", Language: "en"},
+		{Slug: "draft", Language: "en", Listed: true, Draft: true},
+		{Slug: "expired", Language: "en", Listed: true, ExpiryDate: "2000-01-01"},
+	}
+	repo := structs.Repo{
+		Id:              "docs",
+		Languages:       []string{"en"},
+		ArticlesGrouped: map[string][]structs.Article{"en": articles},
+	}
+
+	previous := currentSnapshot.Load()
+	currentSnapshot.Store(newSnapshot([]structs.Repo{repo}, "test", nil))
+	t.Cleanup(func() { currentSnapshot.Store(previous) })
+
+	for slug, want := range map[string]int{
+		"public":   http.StatusOK,
+		"unlisted": http.StatusOK,
+		"draft":    http.StatusNotFound,
+		"expired":  http.StatusNotFound,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/docs/articles/en/"+slug, nil)
+		r = mux.SetURLVars(r, map[string]string{"repoId": "docs", "lang": "en", "slug": slug})
+		w := httptest.NewRecorder()
+
+		HandleArticle(w, r)
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", r.URL.Path, w.Code, want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ package main
 */
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -44,15 +45,35 @@ func main() {
 	r.Use(corsMiddleware)
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "ok", "version": "` + version + `"}`))
+		json.NewEncoder(w).Encode(core.GetStatus(version))
 	})
 	r.HandleFunc("/repos", core.HandleRepos)
+	r.HandleFunc("/admin/cache", core.HandleCacheStats).Methods(http.MethodGet)
+	r.HandleFunc("/admin/cache/purge", core.HandleCachePurge).Methods(http.MethodPost)
+	r.HandleFunc("/admin/cache/warmup", core.HandleCacheWarmup).Methods(http.MethodPost)
+	r.HandleFunc("/admin/preview", core.HandlePreviewToken).Methods(http.MethodPost)
 	r.HandleFunc("/{repoId}", core.HandleRepo)
 	r.HandleFunc("/{repoId}/langs", core.HandleLangs)
+	r.HandleFunc("/{repoId}/translations", core.HandleTranslations)
+	r.HandleFunc("/{repoId}/tags", core.HandleLanguageRedirect("tags"))
+	r.HandleFunc("/{repoId}/tags/{lang}", core.HandleTags)
+	r.HandleFunc("/{repoId}/tags/{lang}/{tag}", core.HandleTag)
+	r.HandleFunc("/{repoId}/authors", core.HandleLanguageRedirect("authors"))
+	r.HandleFunc("/{repoId}/authors/{lang}", core.HandleAuthors)
+	r.HandleFunc("/{repoId}/authors/{lang}/{author}", core.HandleAuthor)
+	r.HandleFunc("/{repoId}/stories", core.HandleLanguageRedirect("stories"))
+	r.HandleFunc("/{repoId}/stories/{lang}", core.HandleStories)
+	r.HandleFunc("/{repoId}/stories/{lang}/{storyId}", core.HandleStory)
+	r.HandleFunc("/{repoId}/articles", core.HandleLanguageRedirect("articles"))
 	r.HandleFunc("/{repoId}/articles/{lang}", core.HandleArticles)
 	r.HandleFunc("/{repoId}/articles/{lang}/{slug}", core.HandleArticle)
+	r.HandleFunc("/{repoId}/articles/{lang}/{slug}/history", core.HandleArticleHistory)
+	r.HandleFunc("/{repoId}/articles/{lang}/{slug}/diff", core.HandleArticleDiff)
+	r.HandleFunc("/{repoId}/changes", core.HandleChanges)
+	r.HandleFunc("/{repoId}/search", core.HandleLanguageRedirect("search"))
 	r.HandleFunc("/{repoId}/search/{lang}", core.HandleSearch)
 
 	http.Handle("/", r)
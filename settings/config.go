@@ -1,6 +1,8 @@
 package settings
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -10,6 +12,49 @@ type Config struct {
 	LocalRepos            []ConfigRepo `json:"localRepos"`
 	BackgroundCacheUpdate bool         `json:"backgroundCacheUpdate"`
 	CacheBackend          string       `json:"cacheBackend"`
+	GitRemoteChangePolicy string       `json:"gitRemoteChangePolicy"`
+
+	// Bearer token of the /admin endpoints, which are disabled without it
+	AdminToken string `json:"adminToken"`
+
+	// HMAC key of the preview tokens, previews are disabled without it
+	PreviewKey string `json:"previewKey"`
+
+	// Language served when none of the requested ones is available, and
+	// the languages to fall back to before it, by language
+	DefaultLanguage   string              `json:"defaultLanguage"`
+	LanguageFallbacks map[string][]string `json:"languageFallbacks"`
+
+	// Cache-Control header by route name, "default" applies to the others
+	CacheControl map[string]string `json:"cacheControl"`
+
+	// Cache entries expiration, 0 means no expiration
+	CacheTTL time.Duration `json:"cacheTTL"`
+
+	// In-process cache chained in front of the redis backend
+	CacheLocalTier string        `json:"cacheLocalTier"`
+	CacheLocalTTL  time.Duration `json:"cacheLocalTTL"`
+
+	// Ristretto specific settings
+	RistrettoMaxCost     int64 `json:"ristrettoMaxCost"`
+	RistrettoNumCounters int64 `json:"ristrettoNumCounters"`
+
+	// BigCache specific settings
+	BigCacheLifeWindow       time.Duration `json:"bigCacheLifeWindow"`
+	BigCacheHardMaxCacheSize int           `json:"bigCacheHardMaxCacheSize"`
+
+	// go-cache specific settings
+	GoCacheDefaultExpiration time.Duration `json:"goCacheDefaultExpiration"`
+	GoCacheCleanupInterval   time.Duration `json:"goCacheCleanupInterval"`
+
+	// Disk specific settings
+	DiskCachePath            string        `json:"diskCachePath"`
+	DiskCacheCleanupInterval time.Duration `json:"diskCacheCleanupInterval"`
+
+	// Memcached specific settings
+	MemcacheServers   []string      `json:"memcacheServers"`
+	MemcacheTimeout   time.Duration `json:"memcacheTimeout"`
+	MemcacheKeyPrefix string        `json:"memcacheKeyPrefix"`
 
 	// Redis specific settings
 	RedisCacheServer   string `json:"redisCacheServer"`
@@ -17,6 +62,7 @@ type Config struct {
 	RedisCacheUsername string `json:"redisCacheUsername"`
 	RedisCachePassword string `json:"redisCachePassword"`
 	RedisCacheDB       int    `json:"redisCacheDB"`
+	RedisCoordination  bool   `json:"redisCoordination"`
 }
 
 type ConfigRepo struct {
@@ -24,6 +70,14 @@ type ConfigRepo struct {
 	Url          string `json:"url"`
 	RootPath     string `json:"rootPath"`
 	FallbackLang string `json:"fallbackLang"`
+
+	// Git checkout settings, ignored for local repositories
+	Branch       string `json:"branch"`
+	Depth        int    `json:"depth"`
+	SingleBranch bool   `json:"singleBranch"`
+	Sparse       bool   `json:"sparse"`
+	Submodules   bool   `json:"submodules"`
+	Lfs          bool   `json:"lfs"`
 }
 
 var Cnf *Config
@@ -31,6 +85,17 @@ var Cnf *Config
 func init() {
 	viper.SetDefault("port", "8080")
 	viper.SetDefault("gitRepo", "")
+	viper.SetDefault("ristrettoMaxCost", 256<<20)
+	viper.SetDefault("ristrettoNumCounters", 100000)
+	viper.SetDefault("bigCacheLifeWindow", "5m")
+	viper.SetDefault("goCacheDefaultExpiration", "5m")
+	viper.SetDefault("goCacheCleanupInterval", "10m")
+	viper.SetDefault("defaultLanguage", "en")
+	viper.SetDefault("cacheLocalTTL", "5m")
+	viper.SetDefault("diskCachePath", "cache/chronos.db")
+	viper.SetDefault("diskCacheCleanupInterval", "10m")
+	viper.SetDefault("memcacheTimeout", "500ms")
+	viper.SetDefault("memcacheKeyPrefix", "chronos:")
 
 	// prod paths
 	viper.AddConfigPath("/etc/chronos/")
@@ -69,11 +134,41 @@ func init() {
 		LocalRepos:            localRepos,
 		BackgroundCacheUpdate: viper.GetBool("backgroundCacheUpdate"),
 		CacheBackend:          viper.GetString("cacheBackend"),
+		GitRemoteChangePolicy: viper.GetString("gitRemoteChangePolicy"),
+
+		AdminToken:   viper.GetString("adminToken"),
+		PreviewKey:   viper.GetString("previewKey"),
+		CacheControl: viper.GetStringMapString("cacheControl"),
+
+		DefaultLanguage:   viper.GetString("defaultLanguage"),
+		LanguageFallbacks: viper.GetStringMapStringSlice("languageFallbacks"),
+
+		CacheTTL: viper.GetDuration("cacheTTL"),
+
+		CacheLocalTier: viper.GetString("cacheLocalTier"),
+		CacheLocalTTL:  viper.GetDuration("cacheLocalTTL"),
+
+		RistrettoMaxCost:     viper.GetInt64("ristrettoMaxCost"),
+		RistrettoNumCounters: viper.GetInt64("ristrettoNumCounters"),
+
+		BigCacheLifeWindow:       viper.GetDuration("bigCacheLifeWindow"),
+		BigCacheHardMaxCacheSize: viper.GetInt("bigCacheHardMaxCacheSize"),
+
+		GoCacheDefaultExpiration: viper.GetDuration("goCacheDefaultExpiration"),
+		GoCacheCleanupInterval:   viper.GetDuration("goCacheCleanupInterval"),
+
+		DiskCachePath:            viper.GetString("diskCachePath"),
+		DiskCacheCleanupInterval: viper.GetDuration("diskCacheCleanupInterval"),
+
+		MemcacheServers:   viper.GetStringSlice("memcacheServers"),
+		MemcacheTimeout:   viper.GetDuration("memcacheTimeout"),
+		MemcacheKeyPrefix: viper.GetString("memcacheKeyPrefix"),
 
 		RedisCacheServer:   viper.GetString("redisCacheServer"),
 		RedisCachePort:     viper.GetString("redisCachePort"),
 		RedisCacheUsername: viper.GetString("redisCacheUsername"),
 		RedisCachePassword: viper.GetString("redisCachePassword"),
 		RedisCacheDB:       viper.GetInt("redisCacheDB"),
+		RedisCoordination:  viper.GetBool("redisCoordination"),
 	}
 }
@@ -9,7 +9,11 @@ package structs
 		Chronos is a simple, fast and lightweight documentation server written in Go.
 */
 
-import "github.com/russross/blackfriday/v2"
+import (
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
 
 type Article struct {
 	StoryId         string
@@ -17,16 +21,34 @@ type Article struct {
 	Previous        string
 	Next            string
 	Listed          bool
+	Draft           bool
 	Title           string
 	Description     string
 	PublicationDate string
+	ExpiryDate      string
 	Authors         []string
 	Tags            []string
+	Weight          int
+	TranslationKey  string
+	SourceHash      string
 	Body            string
+	ContentHash     string // runtime populated field, SHA-256 of the Markdown body
 	Language        string
 	Path            string
 	Url             string
 	Slug            string
+	LastModified    time.Time // runtime populated field, from Git history
+	CreatedAt       time.Time // runtime populated field, from Git history
+	Contributors    []string  // runtime populated field, from Git history
+
+	// runtime populated fields, set when the article is served in place of
+	// a missing translation
+	Fallback          bool   `json:",omitempty"`
+	RequestedLanguage string `json:",omitempty"`
+
+	// runtime populated field, the URLs of the translations of the article
+	// by language
+	Alternates map[string]string `json:",omitempty"`
 }
 
 // ParseBody parses the body of an article and converts it from Markdown to HTML.
@@ -39,10 +61,24 @@ type ArticleHeader struct {
 	StoryId         string   `yaml:"StoryId"`
 	Previous        string   `yaml:"Previous"`
 	Next            string   `yaml:"Next"`
-	Listed          bool     `yaml:"Listed"`
+	Listed          *bool    `yaml:"Listed"` // listed unless false
+	Draft           bool     `yaml:"Draft"`
 	Title           string   `yaml:"Title"`
 	Description     string   `yaml:"Description"`
 	PublicationDate string   `yaml:"PublicationDate"`
+	ExpiryDate      string   `yaml:"ExpiryDate"`
 	Authors         []string `yaml:"Authors"`
 	Tags            []string `yaml:"Tags"`
+	Weight          int      `yaml:"Weight"`
+	TranslationKey  string   `yaml:"TranslationKey"` // matches translations with different slugs
+	SourceHash      string   `yaml:"SourceHash"`     // ContentHash of the source the translation is based on
+}
+
+// ArticleCommit is a Git commit which touched the source file of an article.
+type ArticleCommit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Message string
 }
@@ -14,6 +14,17 @@ type ArticlesResponse struct {
 	Title         string           `json:"title"`
 	SupportedLang []string         `json:"SupportedLang"`
 	Tags          []string         `json:"tags"`
-	Articles      []Article        `json:"articles"`
 	Stories       map[string]Story `json:"stories"`
+	ArticlesPage
+}
+
+// ArticlesPage is a list of articles, or a page of it when paginated.
+type ArticlesPage struct {
+	Articles   any    `json:"articles"` // []Article, or a subset of their fields
+	Total      int    `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"perPage,omitempty"`
+	TotalPages int    `json:"totalPages,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
 }
@@ -0,0 +1,39 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+// CacheStatsResponse is the response struct for the /admin/cache endpoint.
+// Keys and Memory are -1 when the backend does not report them. Hits and
+// Misses count the responses served from memory or rendered, BackendHits and
+// BackendMisses the reads of the backend, done when loading the content
+// cached by another instance.
+type CacheStatsResponse struct {
+	Backend        string
+	ContentVersion string
+	Keys           int64
+	Memory         int64
+	Hits           uint64
+	Misses         uint64
+	HitRatio       float64
+	BackendHits    uint64
+	BackendMisses  uint64
+
+	// in-process tier chained in front of the backend, if any
+	LocalTier   string `json:",omitempty"`
+	LocalKeys   int64  `json:",omitempty"`
+	LocalMemory int64  `json:",omitempty"`
+}
+
+// CacheActionResponse is the response struct for the /admin/cache actions.
+type CacheActionResponse struct {
+	Action         string
+	Purged         int    `json:",omitempty"`
+	ContentVersion string `json:",omitempty"`
+}
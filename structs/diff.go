@@ -0,0 +1,46 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+// DiffChunk is a portion of a diff, Type is one of "equal", "add" or
+// "delete".
+type DiffChunk struct {
+	Type    string
+	Content string
+}
+
+// ArticleDiffResponse is the response struct for the
+// /articles/{lang}/{slug}/diff endpoint.
+type ArticleDiffResponse struct {
+	From     string
+	To       string
+	Language string
+	Slug     string
+	Status   string
+	Patch    string
+	Chunks   []DiffChunk
+}
+
+// ArticleChange is an article added, modified or deleted between two
+// revisions of a repository.
+type ArticleChange struct {
+	Status   string
+	Language string
+	Slug     string
+	Path     string
+}
+
+// ChangesResponse is the response struct for the /changes endpoint.
+type ChangesResponse struct {
+	Since   string
+	From    string
+	To      string
+	Changes []ArticleChange
+}
@@ -0,0 +1,24 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+// LangsResponse is the response struct for the /{repoId}/langs endpoint.
+type LangsResponse struct {
+	SupportedLang []string // the language codes, as used in the routes
+	Languages     []Language
+}
+
+// Language describes a language of a repository.
+type Language struct {
+	Code      string // as used in the routes, the name of its directory
+	Tag       string // canonical BCP 47 tag
+	Name      string // in the language itself
+	Direction string // ltr or rtl
+}
@@ -0,0 +1,21 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import "time"
+
+// PreviewTokenResponse is the response struct for the /admin/preview
+// endpoint.
+type PreviewTokenResponse struct {
+	Token     string
+	ExpiresAt time.Time
+	Repo      string `json:",omitempty"`
+	Branch    string `json:",omitempty"`
+}
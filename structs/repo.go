@@ -10,15 +10,19 @@ package structs
 */
 
 type Repo struct {
-	Id              string
-	Path            string
-	Stories         map[string]Story
-	Articles        map[string]Article
-	ArticlesGrouped map[string][]Article
-	Languages       []string
-	RootPath        string
-	FallbackLang    string
-	FallbackEnabled bool
+	Id                 string
+	Path               string
+	Stories            map[string]Story
+	Tags               map[string]Tag
+	Authors            map[string]Author
+	Articles           map[string]Article
+	ArticlesGrouped    map[string][]Article
+	History            map[string][]ArticleCommit // article path -> commits, newest first
+	Languages          []string
+	RootPath           string
+	FallbackLang       string
+	FallbackEnabled    bool
+	UnresolvedLfsFiles []string // LFS pointers which could not be resolved
 }
 
 func (r *Repo) IsLangSupported(lang string) bool {
@@ -0,0 +1,24 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import "time"
+
+// StatusResponse is the response struct for the / endpoint. Status is
+// "stale" when the last reload failed and the previous content is served.
+type StatusResponse struct {
+	Status          string     `json:"status"`
+	Version         string     `json:"version"`
+	ContentVersion  string     `json:"contentVersion,omitempty"`
+	ContentLoadedAt time.Time  `json:"contentLoadedAt"`
+	ContentAge      int64      `json:"contentAge"` // seconds since the last successful reload
+	LastReloadAt    *time.Time `json:"lastReloadAt,omitempty"`
+	LastReloadError string     `json:"lastReloadError,omitempty"`
+}
@@ -1,9 +1,117 @@
 package structs
 
+import "gopkg.in/yaml.v3"
+
 // Story is the struct that represents a story, a sequence of articles.
 type Story struct {
-	Id          string `yaml:"id"`
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	StartSlug   string `yaml:"startSlug"`
+	Id          string   `yaml:"id"`
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	StartSlug   string   `yaml:"startSlug"`
+	Chapters    []string `yaml:"chapters"` // optional, overrides the Previous and Next links
+
+	// language -> translation, from localized names and descriptions or
+	// from the stories.yml file of the language
+	Translations map[string]StoryTranslation `yaml:"-" json:",omitempty"`
+}
+
+// StoryTranslation overrides the fields of a story in a language, empty
+// fields are not translated.
+type StoryTranslation struct {
+	Name        string
+	Description string
+	StartSlug   string
+	Chapters    []string
+}
+
+// StoryChapter is an article of a story.
+type StoryChapter struct {
+	Number      int
+	Slug        string
+	Title       string
+	Description string
+}
+
+// StoryResponse is the response struct for the /stories endpoints, a story
+// along with its chapters in reading order.
+type StoryResponse struct {
+	Id          string
+	Name        string
+	Description string
+	Chapters    []StoryChapter
+}
+
+// UnmarshalYAML accepts the name and the description of a story either as
+// strings or as maps of language to text, which are then its translations
+// and leave the default text empty.
+func (s *Story) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		Id          string    `yaml:"id"`
+		Name        yaml.Node `yaml:"name"`
+		Description yaml.Node `yaml:"description"`
+		StartSlug   string    `yaml:"startSlug"`
+		Chapters    []string  `yaml:"chapters"`
+	}
+
+	err := value.Decode(&raw)
+	if err != nil {
+		return err
+	}
+
+	*s = Story{
+		Id:        raw.Id,
+		StartSlug: raw.StartSlug,
+		Chapters:  raw.Chapters,
+	}
+
+	names, err := decodeLocalizedText(&raw.Name, &s.Name)
+	if err != nil {
+		return err
+	}
+
+	descriptions, err := decodeLocalizedText(&raw.Description, &s.Description)
+	if err != nil {
+		return err
+	}
+
+	for lang, name := range names {
+		translation := s.Translations[lang]
+		translation.Name = name
+		s.setTranslation(lang, translation)
+	}
+
+	for lang, description := range descriptions {
+		translation := s.Translations[lang]
+		translation.Description = description
+		s.setTranslation(lang, translation)
+	}
+
+	return nil
+}
+
+func (s *Story) setTranslation(lang string, translation StoryTranslation) {
+	if s.Translations == nil {
+		s.Translations = make(map[string]StoryTranslation)
+	}
+
+	s.Translations[lang] = translation
+}
+
+// decodeLocalizedText decodes a string into text, or else returns a map of
+// language to text.
+func decodeLocalizedText(node *yaml.Node, text *string) (map[string]string, error) {
+	switch node.Kind {
+	case 0:
+		return nil, nil // not set
+	case yaml.ScalarNode:
+		return nil, node.Decode(text)
+	}
+
+	var texts map[string]string
+	err := node.Decode(&texts)
+	if err != nil {
+		return nil, err
+	}
+
+	return texts, nil
 }
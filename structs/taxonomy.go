@@ -0,0 +1,52 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+// Tag describes a tag, from the optional tags.yml file of a repository.
+type Tag struct {
+	Id          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// Author describes an author, from the optional authors.yml file of a
+// repository.
+type Author struct {
+	Id     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	Avatar string `yaml:"avatar"`
+	Bio    string `yaml:"bio"`
+	Url    string `yaml:"url"`
+}
+
+// TagSummary is a tag along with the number of its articles.
+type TagSummary struct {
+	Tag
+	Count int
+}
+
+// AuthorSummary is an author along with the number of their articles.
+type AuthorSummary struct {
+	Author
+	Count int
+}
+
+// TagResponse is the response struct for the /tags/{lang}/{tag} endpoint.
+type TagResponse struct {
+	Tag TagSummary `json:"tag"`
+	ArticlesPage
+}
+
+// AuthorResponse is the response struct for the /authors/{lang}/{author}
+// endpoint.
+type AuthorResponse struct {
+	Author AuthorSummary `json:"author"`
+	ArticlesPage
+}
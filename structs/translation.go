@@ -0,0 +1,53 @@
+package structs
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import "time"
+
+// Translation statuses of a source article in a language, and the reasons
+// a translation is outdated.
+const (
+	TranslationMissing  = "missing"
+	TranslationUpToDate = "upToDate"
+	TranslationOutdated = "outdated"
+
+	OutdatedSourceHash    = "sourceHash"
+	OutdatedSourceCommits = "sourceCommits"
+)
+
+// TranslationsResponse is the response struct for the
+// /{repoId}/translations endpoint.
+type TranslationsResponse struct {
+	SourceLanguage string
+	Total          int // articles in the source language
+	Languages      []LanguageCoverage
+}
+
+// LanguageCoverage reports the translation status of the source articles in
+// a language.
+type LanguageCoverage struct {
+	Language string
+	UpToDate int
+	Outdated int
+	Missing  int
+	Articles []TranslationStatus
+}
+
+// TranslationStatus is the translation status of a source article.
+type TranslationStatus struct {
+	Key            string // TranslationKey of the source article, or its slug
+	SourceSlug     string
+	Slug           string `json:",omitempty"`
+	Status         string // missing, upToDate or outdated
+	OutdatedBy     string `json:",omitempty"` // sourceHash or sourceCommits
+	SourceCommits  int    `json:",omitempty"` // commits to the source since the translation
+	SourceModified time.Time
+	Modified       time.Time
+}
@@ -0,0 +1,306 @@
+package utils
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	lib_store "github.com/eko/gocache/lib/v4/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// BboltType represents the storage type as a string value
+	BboltType = "bbolt"
+)
+
+var (
+	bboltEntriesBucket = []byte("entries")
+	bboltTagsBucket    = []byte("tags")
+
+	errBboltNotFound = errors.New("key not found")
+)
+
+// BboltStore is a store persisting the entries in a bbolt database file.
+// Each entry is prefixed by its expiration time, expired entries are
+// deleted when read and by Cleanup.
+type BboltStore struct {
+	db      *bolt.DB
+	options *lib_store.Options
+}
+
+// NewBbolt creates a new store on top of an open bbolt database.
+func NewBbolt(db *bolt.DB, options ...lib_store.Option) (*BboltStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bboltEntriesBucket, bboltTagsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BboltStore{
+		db:      db,
+		options: lib_store.ApplyOptions(options...),
+	}, nil
+}
+
+// Get returns data stored from a given key
+func (s *BboltStore) Get(ctx context.Context, key any) (any, error) {
+	value, _, err := s.GetWithTTL(ctx, key)
+	return value, err
+}
+
+// GetWithTTL returns data stored from a given key and its corresponding TTL
+func (s *BboltStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	var value []byte
+	var expiration time.Time
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		entry := tx.Bucket(bboltEntriesBucket).Get([]byte(key.(string)))
+		if entry == nil {
+			return errBboltNotFound
+		}
+
+		var ok bool
+		expiration, ok = decodeBboltExpiration(entry)
+		if !ok {
+			return errBboltNotFound // truncated entries are left to Cleanup
+		}
+		value = bytes.Clone(entry[8:])
+		return nil
+	})
+	if err == errBboltNotFound {
+		return nil, 0, lib_store.NotFoundWithCause(err)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if expiration.IsZero() {
+		return value, 0, nil
+	}
+
+	ttl := time.Until(expiration)
+	if ttl <= 0 {
+		s.Delete(ctx, key)
+		return nil, 0, lib_store.NotFoundWithCause(errBboltNotFound)
+	}
+
+	return value, ttl, nil
+}
+
+// Set defines data in the database for given key identifier
+func (s *BboltStore) Set(ctx context.Context, key any, value any, options ...lib_store.Option) error {
+	opts := lib_store.ApplyOptionsWithDefault(s.options, options...)
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported value type %T", value)
+	}
+
+	var expiration time.Time
+	if opts.Expiration > 0 {
+		expiration = time.Now().Add(opts.Expiration)
+	}
+
+	entry := make([]byte, 8+len(data))
+	if !expiration.IsZero() {
+		binary.BigEndian.PutUint64(entry, uint64(expiration.UnixNano()))
+	}
+	copy(entry[8:], data)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(bboltEntriesBucket).Put([]byte(key.(string)), entry)
+		if err != nil {
+			return err
+		}
+
+		tags := tx.Bucket(bboltTagsBucket)
+		for _, tag := range opts.Tags {
+			err := tags.Put(bboltTagKey(tag, key.(string)), nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete removes data from the database for given key identifier
+func (s *BboltStore) Delete(ctx context.Context, key any) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bboltEntriesBucket).Delete([]byte(key.(string)))
+	})
+}
+
+// Invalidate invalidates some cache data in the database for given options
+func (s *BboltStore) Invalidate(ctx context.Context, options ...lib_store.InvalidateOption) error {
+	opts := lib_store.ApplyInvalidateOptions(options...)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(bboltEntriesBucket)
+		tags := tx.Bucket(bboltTagsBucket)
+
+		for _, tag := range opts.Tags {
+			prefix := bboltTagKey(tag, "")
+
+			var tagKeys [][]byte
+			cursor := tags.Cursor()
+			for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+				tagKeys = append(tagKeys, bytes.Clone(k))
+			}
+
+			for _, tagKey := range tagKeys {
+				err := entries.Delete(tagKey[len(prefix):])
+				if err != nil {
+					return err
+				}
+
+				err = tags.Delete(tagKey)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Clear resets all data in the store
+func (s *BboltStore) Clear(ctx context.Context) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bboltEntriesBucket, bboltTagsBucket} {
+			err := tx.DeleteBucket(bucket)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.CreateBucket(bucket)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetType returns the store type
+func (s *BboltStore) GetType() string {
+	return BboltType
+}
+
+// Cleanup deletes the expired entries and the tags of the deleted ones,
+// returning how many entries were deleted.
+func (s *BboltStore) Cleanup() (int, error) {
+	var deleted int
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(bboltEntriesBucket)
+		tags := tx.Bucket(bboltTagsBucket)
+
+		var expired [][]byte
+		now := time.Now()
+		err := entries.ForEach(func(k, v []byte) error {
+			expiration, ok := decodeBboltExpiration(v)
+			if !ok || !expiration.IsZero() && expiration.Before(now) {
+				expired = append(expired, bytes.Clone(k))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			err := entries.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+		deleted = len(expired)
+
+		var orphans [][]byte
+		err = tags.ForEach(func(k, v []byte) error {
+			_, key, _ := bytes.Cut(k, []byte{0})
+			if entries.Get(key) == nil {
+				orphans = append(orphans, bytes.Clone(k))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range orphans {
+			err := tags.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// Usage returns the number of entries and the size of the database file.
+func (s *BboltStore) Usage() (int64, int64, error) {
+	var keys, size int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		keys = int64(tx.Bucket(bboltEntriesBucket).Stats().KeyN)
+		size = tx.Size()
+		return nil
+	})
+
+	return keys, size, err
+}
+
+// bboltTagKey returns the key recording that key is tagged with tag.
+func bboltTagKey(tag string, key string) []byte {
+	return []byte(tag + "\x00" + key)
+}
+
+// decodeBboltExpiration returns the expiration of an entry, zero if it has
+// none, reporting whether the entry is long enough to hold one.
+func decodeBboltExpiration(entry []byte) (time.Time, bool) {
+	if len(entry) < 8 {
+		return time.Time{}, false
+	}
+
+	nanos := binary.BigEndian.Uint64(entry[:8])
+	if nanos == 0 {
+		return time.Time{}, true
+	}
+
+	return time.Unix(0, int64(nanos)), true
+}
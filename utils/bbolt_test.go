@@ -0,0 +1,48 @@
+package utils
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBboltTruncatedEntry(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cache.db"), 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	s, err := NewBbolt(db)
+	if err != nil {
+		t.Fatalf("NewBbolt() failed: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bboltEntriesBucket).Put([]byte("truncated"), []byte{1, 2, 3})
+	})
+	if err != nil {
+		t.Fatalf("unable to write entry: %v", err)
+	}
+
+	if _, err := s.Get(context.Background(), "truncated"); err == nil {
+		t.Error("Get() of a truncated entry succeeded, want not found")
+	}
+
+	deleted, err := s.Cleanup()
+	if err != nil || deleted != 1 {
+		t.Errorf("Cleanup() = %d, %v, want the truncated entry deleted", deleted, err)
+	}
+}
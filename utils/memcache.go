@@ -0,0 +1,319 @@
+package utils
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	lib_store "github.com/eko/gocache/lib/v4/store"
+)
+
+// MemcacheClientInterface represents a bradfitz/gomemcache client, so that
+// the store can be backed by an in-memory fake.
+type MemcacheClientInterface interface {
+	Get(key string) (*memcache.Item, error)
+	Set(item *memcache.Item) error
+	Add(item *memcache.Item) error
+	CompareAndSwap(item *memcache.Item) error
+	Delete(key string) error
+}
+
+const (
+	// MemcacheType represents the storage type as a string value
+	MemcacheType = "memcache"
+	// MemcacheTagPattern represents the tag pattern to be used as a key in specified storage
+	MemcacheTagPattern = "gocache_tag_%s"
+
+	// memcacheMaxKeyLength is the longest key accepted by memcached
+	memcacheMaxKeyLength = 250
+	// memcacheMaxRelativeExpiration is the longest expiration memcached
+	// accepts in seconds, longer ones are read as Unix timestamps
+	memcacheMaxRelativeExpiration = 30 * 24 * time.Hour
+	// memcacheTagTTL is the expiration of the tag keys
+	memcacheTagTTL = 720 * time.Hour
+	// memcacheCASRetries is how many times a tag update is retried when it
+	// races with another one
+	memcacheCASRetries = 5
+	// memcacheGenerationKey holds the generation of the keys of a prefix
+	memcacheGenerationKey = "generation"
+)
+
+// MemcacheStore is a store for memcached. Values are stored as raw bytes,
+// so []byte values round-trip unchanged, and keys are prefixed, then
+// namespaced by a generation which Clear replaces, so that the keys of the
+// previous one are no longer read and expire. Other keys of the servers are
+// left alone.
+type MemcacheStore struct {
+	client  MemcacheClientInterface
+	prefix  string
+	options *lib_store.Options
+}
+
+// NewMemcache creates a new store to memcached instance(s), prefixing all
+// the keys with prefix.
+func NewMemcache(client MemcacheClientInterface, prefix string, options ...lib_store.Option) *MemcacheStore {
+	return &MemcacheStore{
+		client:  client,
+		prefix:  prefix,
+		options: lib_store.ApplyOptions(options...),
+	}
+}
+
+// Get returns data stored from a given key
+func (s *MemcacheStore) Get(ctx context.Context, key any) (any, error) {
+	itemKey, err := s.key(key.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.client.Get(itemKey)
+	if err == memcache.ErrCacheMiss {
+		return nil, lib_store.NotFoundWithCause(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item.Value, nil
+}
+
+// GetWithTTL returns data stored from a given key. Memcached does not
+// report the remaining TTL, so it is always 0.
+func (s *MemcacheStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	value, err := s.Get(ctx, key)
+	return value, 0, err
+}
+
+// Set defines data in memcached for given key identifier
+func (s *MemcacheStore) Set(ctx context.Context, key any, value any, options ...lib_store.Option) error {
+	opts := lib_store.ApplyOptionsWithDefault(s.options, options...)
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("memcache store only supports []byte and string values")
+	}
+
+	itemKey, err := s.key(key.(string))
+	if err != nil {
+		return err
+	}
+
+	err = s.client.Set(&memcache.Item{
+		Key:        itemKey,
+		Value:      data,
+		Expiration: memcacheExpiration(opts.Expiration),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range opts.Tags {
+		err := s.addTag(tag, key.(string))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addTag records key in the list of the keys tagged with tag, retrying on
+// concurrent updates of the list.
+func (s *MemcacheStore) addTag(tag string, key string) error {
+	tagKey, err := s.key(fmt.Sprintf(MemcacheTagPattern, tag))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < memcacheCASRetries; i++ {
+		item, err := s.client.Get(tagKey)
+		if err == memcache.ErrCacheMiss {
+			err = s.client.Add(&memcache.Item{
+				Key:        tagKey,
+				Value:      []byte(key),
+				Expiration: memcacheExpiration(memcacheTagTTL),
+			})
+			if err == memcache.ErrNotStored {
+				continue // added concurrently
+			}
+
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, tagged := range strings.Split(string(item.Value), "\n") {
+			if tagged == key {
+				return nil
+			}
+		}
+
+		item.Value = append(item.Value, "\n"+key...)
+		item.Expiration = memcacheExpiration(memcacheTagTTL)
+		err = s.client.CompareAndSwap(item)
+		if err == memcache.ErrCASConflict || err == memcache.ErrNotStored {
+			continue
+		}
+
+		return err
+	}
+
+	return errors.New("too many concurrent updates of tag " + tag)
+}
+
+// Delete removes data from memcached for given key identifier
+func (s *MemcacheStore) Delete(ctx context.Context, key any) error {
+	itemKey, err := s.key(key.(string))
+	if err != nil {
+		return err
+	}
+
+	err = s.client.Delete(itemKey)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+
+	return err
+}
+
+// Invalidate invalidates some cache data in memcached for given options
+func (s *MemcacheStore) Invalidate(ctx context.Context, options ...lib_store.InvalidateOption) error {
+	opts := lib_store.ApplyInvalidateOptions(options...)
+
+	for _, tag := range opts.Tags {
+		tagKey := fmt.Sprintf(MemcacheTagPattern, tag)
+
+		itemKey, err := s.key(tagKey)
+		if err != nil {
+			return err
+		}
+
+		item, err := s.client.Get(itemKey)
+		if err != nil {
+			continue
+		}
+
+		for _, cacheKey := range strings.Split(string(item.Value), "\n") {
+			s.Delete(ctx, cacheKey)
+		}
+
+		s.Delete(ctx, tagKey)
+	}
+
+	return nil
+}
+
+// GetType returns the store type
+func (s *MemcacheStore) GetType() string {
+	return MemcacheType
+}
+
+// Clear resets all data in the store by starting a new generation of its
+// keys, those of other applications sharing the memcached servers are left
+// alone.
+func (s *MemcacheStore) Clear(ctx context.Context) error {
+	return s.client.Set(&memcache.Item{
+		Key:   s.prefix + memcacheGenerationKey,
+		Value: []byte(newMemcacheGeneration()),
+	})
+}
+
+// generation returns the current generation of the keys, starting one when
+// there is none yet or it was evicted.
+func (s *MemcacheStore) generation() (string, error) {
+	generationKey := s.prefix + memcacheGenerationKey
+
+	for i := 0; i < memcacheCASRetries; i++ {
+		item, err := s.client.Get(generationKey)
+		if err == nil {
+			return string(item.Value), nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return "", err
+		}
+
+		generation := newMemcacheGeneration()
+		err = s.client.Add(&memcache.Item{Key: generationKey, Value: []byte(generation)})
+		if err == memcache.ErrNotStored {
+			continue // started concurrently
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return generation, nil
+	}
+
+	return "", errors.New("too many concurrent updates of the key generation")
+}
+
+// newMemcacheGeneration returns a generation distinct from the previous
+// ones, so that an evicted generation never brings older keys back.
+func newMemcacheGeneration() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// key returns the memcached key for key, prefixed and namespaced by the
+// current generation. Keys memcached would reject, too long or containing
+// spaces or control characters, are replaced by their hash.
+func (s *MemcacheStore) key(key string) (string, error) {
+	generation, err := s.generation()
+	if err != nil {
+		return "", err
+	}
+
+	prefixed := s.prefix + generation + ":" + key
+	if len(prefixed) <= memcacheMaxKeyLength && isValidMemcacheKey(prefixed) {
+		return prefixed, nil
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return s.prefix + generation + ":sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func isValidMemcacheKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// memcacheExpiration converts an expiration to the memcached format, in
+// seconds or as a Unix timestamp past 30 days.
+func memcacheExpiration(expiration time.Duration) int32 {
+	if expiration <= 0 {
+		return 0
+	}
+
+	if expiration > memcacheMaxRelativeExpiration {
+		return int32(time.Now().Add(expiration).Unix())
+	}
+
+	// 0 would mean no expiration
+	return int32(math.Ceil(expiration.Seconds()))
+}
@@ -0,0 +1,284 @@
+package utils
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <send@mirko.pm>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Chronos is a simple, fast and lightweight documentation server written in Go.
+*/
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	lib_store "github.com/eko/gocache/lib/v4/store"
+)
+
+// fakeMemcache is an in-memory MemcacheClientInterface with a settable
+// clock, following the memcached expiration and CAS semantics.
+type fakeMemcache struct {
+	mu      sync.Mutex
+	now     time.Time
+	items   map[string]fakeMemcacheItem
+	fetched map[*memcache.Item]uint64 // items returned by Get -> version
+	version uint64
+}
+
+type fakeMemcacheItem struct {
+	value     []byte
+	expiresAt time.Time
+	version   uint64
+}
+
+func newFakeMemcache() *fakeMemcache {
+	return &fakeMemcache{
+		now:     time.Now(),
+		items:   make(map[string]fakeMemcacheItem),
+		fetched: make(map[*memcache.Item]uint64),
+	}
+}
+
+func (f *fakeMemcache) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// lookup returns the live item of key, the lock being held.
+func (f *fakeMemcache) lookup(key string) (fakeMemcacheItem, bool) {
+	item, ok := f.items[key]
+	if ok && !item.expiresAt.IsZero() && !f.now.Before(item.expiresAt) {
+		delete(f.items, key)
+		return item, false
+	}
+
+	return item, ok
+}
+
+// store writes an item, the lock being held.
+func (f *fakeMemcache) store(item *memcache.Item) error {
+	if len(item.Key) > memcacheMaxKeyLength || !isValidMemcacheKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+
+	var expiresAt time.Time
+	switch {
+	case item.Expiration <= 0:
+	case time.Duration(item.Expiration)*time.Second > memcacheMaxRelativeExpiration:
+		expiresAt = time.Unix(int64(item.Expiration), 0)
+	default:
+		expiresAt = f.now.Add(time.Duration(item.Expiration) * time.Second)
+	}
+
+	f.version++
+	f.items[item.Key] = fakeMemcacheItem{
+		value:     append([]byte(nil), item.Value...),
+		expiresAt: expiresAt,
+		version:   f.version,
+	}
+
+	return nil
+}
+
+func (f *fakeMemcache) Get(key string) (*memcache.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	item, ok := f.lookup(key)
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+
+	fetched := &memcache.Item{Key: key, Value: append([]byte(nil), item.value...)}
+	f.fetched[fetched] = item.version
+
+	return fetched, nil
+}
+
+func (f *fakeMemcache) Set(item *memcache.Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.store(item)
+}
+
+func (f *fakeMemcache) Add(item *memcache.Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.lookup(item.Key); ok {
+		return memcache.ErrNotStored
+	}
+
+	return f.store(item)
+}
+
+func (f *fakeMemcache) CompareAndSwap(item *memcache.Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, ok := f.lookup(item.Key)
+	if !ok {
+		return memcache.ErrNotStored
+	}
+	if version, fetched := f.fetched[item]; !fetched || version != current.version {
+		return memcache.ErrCASConflict
+	}
+
+	return f.store(item)
+}
+
+func (f *fakeMemcache) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.lookup(key); !ok {
+		return memcache.ErrCacheMiss
+	}
+	delete(f.items, key)
+
+	return nil
+}
+
+func TestMemcacheGetSet(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMemcache()
+	s := NewMemcache(client, "chronos:")
+
+	_, err := s.Get(ctx, "missing")
+	if !errors.Is(err, memcache.ErrCacheMiss) {
+		t.Errorf("Get() of a missing key = %v, want a cache miss", err)
+	}
+
+	err = s.Set(ctx, "key", []byte("value"))
+	if err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	value, err := s.Get(ctx, "key")
+	if err != nil || string(value.([]byte)) != "value" {
+		t.Errorf("Get() = %q, %v, want value", value, err)
+	}
+
+	for key := range client.items {
+		if !strings.HasPrefix(key, "chronos:") {
+			t.Errorf("key %s was not prefixed", key)
+		}
+	}
+
+	long := strings.Repeat("k", 300) + " with spaces"
+	err = s.Set(ctx, long, "long")
+	if err != nil {
+		t.Fatalf("Set() of a long key failed: %v", err)
+	}
+
+	value, err = s.Get(ctx, long)
+	if err != nil || string(value.([]byte)) != "long" {
+		t.Errorf("Get() of a long key = %q, %v, want long", value, err)
+	}
+
+	err = s.Delete(ctx, "key")
+	if err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "key"); err == nil {
+		t.Error("Get() of a deleted key succeeded")
+	}
+}
+
+func TestMemcacheTTL(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMemcache()
+	s := NewMemcache(client, "")
+
+	err := s.Set(ctx, "short", "value", lib_store.WithExpiration(1500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	err = s.Set(ctx, "long", "value", lib_store.WithExpiration(60*24*time.Hour))
+	if err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	client.advance(time.Second)
+	if _, err := s.Get(ctx, "short"); err != nil {
+		t.Errorf("Get() before the expiration failed: %v", err)
+	}
+
+	// sub-second expirations are rounded up, not down to no expiration
+	client.advance(time.Second)
+	if _, err := s.Get(ctx, "short"); err == nil {
+		t.Error("Get() after the expiration succeeded")
+	}
+
+	// expirations past 30 days are Unix timestamps
+	client.advance(45 * 24 * time.Hour)
+	if _, err := s.Get(ctx, "long"); err != nil {
+		t.Errorf("Get() before a long expiration failed: %v", err)
+	}
+	client.advance(30 * 24 * time.Hour)
+	if _, err := s.Get(ctx, "long"); err == nil {
+		t.Error("Get() after a long expiration succeeded")
+	}
+}
+
+func TestMemcacheInvalidateAndClear(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMemcache()
+	s := NewMemcache(client, "chronos:")
+
+	// a key of another application sharing the server
+	err := client.Set(&memcache.Item{Key: "other:key", Value: []byte("value")})
+	if err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		tags := []string{"all"}
+		if key != "c" {
+			tags = append(tags, "ab")
+		}
+
+		err := s.Set(ctx, key, key, lib_store.WithTags(tags))
+		if err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+
+	err = s.Invalidate(ctx, lib_store.WithInvalidateTags([]string{"ab"}))
+	if err != nil {
+		t.Fatalf("Invalidate() failed: %v", err)
+	}
+
+	for key, want := range map[string]bool{"a": false, "b": false, "c": true} {
+		if _, err := s.Get(ctx, key); (err == nil) != want {
+			t.Errorf("Get(%s) after Invalidate() = %v, want present: %v", key, err, want)
+		}
+	}
+
+	err = s.Clear(ctx)
+	if err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "c"); err == nil {
+		t.Error("Get() after Clear() succeeded")
+	}
+	if _, err := client.Get("other:key"); err != nil {
+		t.Errorf("Clear() removed the key of another application: %v", err)
+	}
+
+	err = s.Set(ctx, "c", "c")
+	if err != nil {
+		t.Fatalf("Set() after Clear() failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "c"); err != nil {
+		t.Errorf("Get() after Clear() and Set() failed: %v", err)
+	}
+}
@@ -23,7 +23,7 @@ type RedisClientInterface interface {
 	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
 	Set(ctx context.Context, key string, values any, expiration time.Duration) *redis.StatusCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
-	FlushAll(ctx context.Context) *redis.StatusCmd
+	FlushDB(ctx context.Context) *redis.StatusCmd
 	SAdd(ctx context.Context, key string, members ...any) *redis.IntCmd
 	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
 }
@@ -66,7 +66,9 @@ func (s *RedisStore) Get(ctx context.Context, key any) (any, error) {
 	return decoded, nil
 }
 
-// GetWithTTL returns data stored from a given key and its corresponding TTL
+// GetWithTTL returns data stored from a given key and its corresponding TTL.
+// Unlike Get, values which are not base64 encoded are an error, since the
+// chain cache would otherwise write them back as zero values.
 func (s *RedisStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
 	object, err := s.client.Get(ctx, key.(string)).Result()
 	if err == redis.Nil {
@@ -81,7 +83,12 @@ func (s *RedisStore) GetWithTTL(ctx context.Context, key any) (any, time.Duratio
 		return nil, 0, err
 	}
 
-	return object, ttl, err
+	decoded, err := base64.StdEncoding.DecodeString(object)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode %s: %v", key, err)
+	}
+
+	return decoded, ttl, nil
 }
 
 // Set defines data in Redis for given key identifier
@@ -148,9 +155,10 @@ func (s *RedisStore) GetType() string {
 	return RedisType
 }
 
-// Clear resets all data in the store
+// Clear resets all data in the store, only flushing the selected database
+// since the server may be shared
 func (s *RedisStore) Clear(ctx context.Context) error {
-	if err := s.client.FlushAll(ctx).Err(); err != nil {
+	if err := s.client.FlushDB(ctx).Err(); err != nil {
 		return err
 	}
 